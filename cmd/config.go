@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/foomo/gograpple/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	configValidateCmd.Flags().StringVar(&flagSaveDir, "save", ".", "directory of the saved configurations")
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+var (
+	configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "manage the configuration files",
+	}
+	configValidateCmd = &cobra.Command{
+		Use:   "validate [file...]",
+		Short: "validate configuration files without touching the cluster, defaults to the files in the save directory",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			files := args
+			if len(files) == 0 {
+				files = savedConfigs(flagSaveDir)
+			}
+			if len(files) == 0 {
+				return fmt.Errorf("no configuration files in %q", flagSaveDir)
+			}
+			return validateConfigs(files)
+		},
+	}
+)
+
+// savedConfigs returns the existing configuration files of dir
+func savedConfigs(dir string) []string {
+	var files []string
+	for _, name := range []string{"gograpple-patch.yaml", "gograpple-attach.yaml", config.ProjectFile} {
+		fp := path.Join(dir, name)
+		if _, err := os.Stat(fp); err == nil {
+			files = append(files, fp)
+		}
+	}
+	return files
+}
+
+// validateConfigs prints the problems of every file and fails if any file is invalid
+func validateConfigs(files []string) error {
+	invalid := 0
+	for _, fp := range files {
+		err := config.ValidateFile(fp)
+		var validationErr *config.ValidationError
+		switch {
+		case err == nil:
+			fmt.Printf("%v: ok\n", fp)
+		case errors.As(err, &validationErr):
+			invalid++
+			for _, p := range validationErr.Problems {
+				fmt.Println(p)
+			}
+		default:
+			return err
+		}
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%v of %v configuration files are invalid", invalid, len(files))
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/foomo/gograpple/internal/config"
+	"github.com/foomo/gograpple/internal/delve"
+	"github.com/foomo/gograpple/internal/grapple"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	debugCmd.Flags().BoolVar(&flagConnect, "connect", false, "connect a terminal debugger to a running debug session")
+	debugCmd.Flags().StringVar(&flagSaveDir, "save", ".", "directory of the saved configuration")
+	debugCmd.Flags().BoolVar(&flagYes, "yes", false, "apply the deployment patch without confirmation")
+	debugCmd.Flags().BoolVar(&flagDryRun, "dry-run", false, "print the patch and the commands it would run without changing anything")
+	rootCmd.AddCommand(debugCmd)
+}
+
+var (
+	flagConnect bool
+	debugCmd    = &cobra.Command{
+		Use:         "debug",
+		Short:       "run the patch debug session or connect a terminal debugger to it",
+		Annotations: sessionAnnotations,
+		Args:        cobra.MinimumNArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if flagConnect {
+				return connectDebug(flagSaveDir)
+			}
+			return patchDebug(flagSaveDir)
+		},
+	}
+)
+
+// sessionAddr returns the address of a running debug session, allocated ports are read from the session state
+func sessionAddr(c config.PatchConfig) (string, int, error) {
+	host, port, err := c.Addr()
+	if err != nil {
+		return "", 0, err
+	}
+	if port == 0 {
+		s, err := grapple.LoadSession(c.Cluster, c.Namespace, c.Deployment)
+		if err != nil {
+			return "", 0, fmt.Errorf("listen_addr %q allocates the port per session and no session state was found: %w", c.ListenAddr, err)
+		}
+		return s.Host, s.LocalPort, nil
+	}
+	return host, port, nil
+}
+
+func connectDebug(baseDir string) error {
+	var c config.PatchConfig
+	if err := config.Load(path.Join(baseDir, "gograpple-patch.yaml"), &c); err != nil {
+		return err
+	}
+	if protocol(c.Protocol) == delve.ProtocolDAP {
+		return fmt.Errorf("the terminal debugger needs the %q protocol", delve.ProtocolRPC)
+	}
+	host, port, err := sessionAddr(c)
+	if err != nil {
+		return err
+	}
+	return grapple.Connect(newLogEntry(flagDebug), c.SourcePath, host, port, c.TrimPath)
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"path"
+
+	"github.com/foomo/gograpple/internal/config"
+	"github.com/foomo/gograpple/internal/kubectl"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	fetchCmd.Flags().StringVar(&flagFetchDir, "out", ".", "local directory to copy the files into")
+	fetchCmd.Flags().StringVar(&flagSaveDir, "save", ".", "directory of the saved patch configuration")
+	rootCmd.AddCommand(fetchCmd)
+}
+
+var (
+	flagFetchDir string
+	fetchCmd     = &cobra.Command{
+		Use:   "fetch [path...]",
+		Short: "copy files from the patched container",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var c config.PatchConfig
+			if err := config.Load(path.Join(flagSaveDir, "gograpple-patch.yaml"), &c); err != nil {
+				return err
+			}
+			if err := kubectl.SetContext(c.Cluster); err != nil {
+				return err
+			}
+			g, err := newGrapple(c.Namespace, c.Deployment)
+			if err != nil {
+				return err
+			}
+			return g.Fetch("", c.Container, flagFetchDir, args)
+		},
+	}
+)
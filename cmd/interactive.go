@@ -1,27 +1,43 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
 	"path"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/foomo/gograpple/internal/config"
+	"github.com/foomo/gograpple/internal/delve"
+	"github.com/foomo/gograpple/internal/exec"
 	"github.com/foomo/gograpple/internal/grapple"
 	"github.com/foomo/gograpple/internal/kubectl"
+	"github.com/foomo/gograpple/internal/lifecycle"
 	"github.com/spf13/cobra"
 )
 
 func init() {
 	interactiveCmd.Flags().BoolVar(&flagAttach, "attach", false, "debug with attach (default will patch)")
 	interactiveCmd.Flags().StringVar(&flagSaveDir, "save", ".", "directory to save interactive configuration")
+	interactiveCmd.Flags().BoolVar(&flagYes, "yes", false, "apply the deployment patch without confirmation")
+	interactiveCmd.Flags().BoolVar(&flagDryRun, "dry-run", false, "print the patch and the commands it would run without changing anything")
 	rootCmd.AddCommand(interactiveCmd)
 }
 
 var (
 	flagAttach     bool
 	flagSaveDir    string
+	flagDryRun     bool
+	flagYes        bool
 	interactiveCmd = &cobra.Command{
-		Use:   "interactive",
-		Short: "setup and run patch or attach interactively",
-		Args:  cobra.MinimumNArgs(0),
+		Use:         "interactive",
+		Short:       "setup and run patch or attach interactively",
+		Annotations: sessionAnnotations,
+		Args:        cobra.MinimumNArgs(0),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if flagAttach {
 				return attachDebug(flagSaveDir)
@@ -41,7 +57,12 @@ func attachDebug(baseDir string) error {
 	if err != nil {
 		return err
 	}
-	g, err := grapple.NewGrapple(newLogEntry(flagDebug), c.Namespace, c.Deployment)
+	return runAttach(c)
+}
+
+// runAttach attaches to the process of the deployment configured in c
+func runAttach(c config.AttachConfig) error {
+	g, err := newGrapple(c.Namespace, c.Deployment)
 	if err != nil {
 		return err
 	}
@@ -52,7 +73,7 @@ func attachDebug(baseDir string) error {
 	if err := kubectl.SetContext(c.Cluster); err != nil {
 		return err
 	}
-	return g.Attach(c.Namespace, c.Deployment, c.Container, c.AttachTo, c.Arch, host, port, flagDebug)
+	return g.Attach(c.Namespace, c.Deployment, c.Container, c.AttachTo, c.Arch, host, port, c.SourcePath, c.BuildPath, protocol(c.Protocol), flagDebug)
 }
 
 func patchDebug(baseDir string) error {
@@ -68,10 +89,16 @@ func patchDebug(baseDir string) error {
 	if &c == nil {
 		return nil
 	}
+	return runPatch(baseDir, c)
+}
+
+// runPatch patches the deployment configured in c and runs the debug session on it,
+// relative paths of c are resolved from baseDir
+func runPatch(baseDir string, c config.PatchConfig) error {
 	if err := kubectl.SetContext(c.Cluster); err != nil {
 		return err
 	}
-	g, err := grapple.NewGrapple(newLogEntry(flagDebug), c.Namespace, c.Deployment)
+	g, err := newGrapple(c.Namespace, c.Deployment)
 	if err != nil {
 		return err
 	}
@@ -79,9 +106,133 @@ func patchDebug(baseDir string) error {
 	if err != nil {
 		return err
 	}
-	if err := g.Patch(c.Image, c.Container, nil); err != nil {
+	env, err := c.Environ(baseDir)
+	if err != nil {
+		return err
+	}
+	mounts, err := grapple.ValidateMounts(baseDir, c.Mounts)
+	if err != nil {
+		return err
+	}
+	if !flagYes {
+		g.ConfirmPatch(confirmPatch)
+	}
+	if flagDryRun {
+		r := exec.NewRecorder()
+		g.DryRun(r)
+		if err := g.Patch(c.Image, c.Container, mounts, probesOrDrop(c.Probes), customization(baseDir, c)); err != nil {
+			return err
+		}
+		r.Print(os.Stdout)
+		return nil
+	}
+	// registered before patching, so an interrupted patch is undone as well
+	lifecycle.Register("rollback deployment", 2*time.Minute, g.Undo)
+	if err := g.Patch(c.Image, c.Container, mounts, probesOrDrop(c.Probes), customization(baseDir, c)); err != nil {
 		return err
 	}
-	defer g.Rollback()
-	return g.Delve("", c.Container, c.SourcePath, nil, host, port, c.LaunchVscode, c.DelveContinue)
+	return runDebug(g, c, mounts, env, host, port)
+}
+
+// runDebug runs the debug session on the patched deployment, the caller registers its rollback
+func runDebug(g *grapple.Grapple, c config.PatchConfig, mounts []grapple.Mount, env []string, host string, port int) error {
+	g.Cluster(c.Cluster)
+	if len(mounts) > 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go g.SyncMounts(ctx, c.Container, mounts)
+	}
+	return g.Delve(grapple.DelveOptions{
+		Container:    c.Container,
+		SourcePath:   c.SourcePath,
+		Args:         c.Args,
+		AppendArgs:   c.AppendArgs(),
+		Env:          env,
+		Host:         host,
+		Port:         port,
+		IDE:          ideOrNone(c.IDE),
+		LaunchJSON:   c.LaunchJSON,
+		RunConfig:    c.RunConfig,
+		Continue:     c.DelveContinue,
+		TrimPath:     c.TrimPath,
+		Protocol:     protocol(c.Protocol),
+		PprofPort:    injectedPprofPort(c),
+		StreamOutput: c.StreamOutput,
+	})
+}
+
+// customization resolves the patch files of c relative to baseDir
+func customization(baseDir string, c config.PatchConfig) grapple.Customization {
+	custom := grapple.Customization{Vars: c.Vars}
+	if c.PatchTemplate != "" {
+		custom.PatchTemplate = relativeTo(baseDir, c.PatchTemplate)
+	}
+	if c.Dockerfile != "" {
+		custom.Dockerfile = relativeTo(baseDir, c.Dockerfile)
+	}
+	for _, o := range c.Overlays {
+		custom.Overlays = append(custom.Overlays, grapple.Overlay{Type: o.Type, Patch: o.Patch})
+	}
+	return custom
+}
+
+func relativeTo(baseDir, file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(baseDir, file)
+}
+
+// confirmPatch prints the spec diff and asks whether the patch should be applied
+func confirmPatch(diff string) (bool, error) {
+	if diff == "" {
+		fmt.Println("the patch does not change the deployment spec")
+	} else {
+		fmt.Print(diff)
+	}
+	fmt.Print("apply the patch? [y/N] ")
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// protocol defaults configs saved without a protocol to json-rpc
+func protocol(p string) string {
+	if p == "" {
+		return delve.ProtocolRPC
+	}
+	return p
+}
+
+// probesOrDrop defaults configs saved without probes to the previous behaviour of dropping them
+func probesOrDrop(probes string) string {
+	if probes == "" {
+		return grapple.ProbesDrop
+	}
+	return probes
+}
+
+func ideOrNone(ide string) string {
+	if ide == "" {
+		return grapple.IDENone
+	}
+	return ide
+}
+
+// injectedPprofPort returns the port for the injected pprof listener, 0 disables the injection
+func injectedPprofPort(c config.PatchConfig) int {
+	if !c.PprofInject {
+		return 0
+	}
+	return pprofPort(c.PprofPort)
+}
+
+func pprofPort(port int) int {
+	if port == 0 {
+		return grapple.DefaultPprofPort
+	}
+	return port
 }
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/foomo/gograpple/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	patchCmd.Flags().BoolVar(&flagAttach, "attach", false, "create a new target with attach (default will patch)")
+	patchCmd.Flags().StringVar(&flagSaveDir, "save", ".", "directory of the project configuration")
+	patchCmd.Flags().BoolVar(&flagYes, "yes", false, "apply the deployment patch without confirmation")
+	patchCmd.Flags().BoolVar(&flagDryRun, "dry-run", false, "print the patch and the commands it would run without changing anything")
+	rootCmd.AddCommand(patchCmd)
+}
+
+var (
+	patchCmd = &cobra.Command{
+		Use:         "patch [target]",
+		Short:       "run a named target of the project configuration, new targets are set up interactively",
+		Annotations: sessionAnnotations,
+		Args:        cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fp := path.Join(flagSaveDir, config.ProjectFile)
+			if len(args) == 0 {
+				return listTargets(fp)
+			}
+			return targetDebug(flagSaveDir, fp, args[0])
+		},
+	}
+)
+
+func listTargets(fp string) error {
+	p, err := config.LoadProject(fp)
+	if err != nil {
+		return err
+	}
+	if len(p.Targets) == 0 {
+		return fmt.Errorf("no targets in %q, add one with gograpple patch <target>", fp)
+	}
+	for _, name := range p.TargetNames() {
+		kind, err := p.Kind(name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%v (%v)\n", name, kind)
+	}
+	return nil
+}
+
+func targetDebug(baseDir, fp, name string) error {
+	p, err := config.LoadProject(fp)
+	if err != nil {
+		return err
+	}
+	kind := config.TargetPatch
+	if _, ok := p.Targets[name]; ok {
+		if kind, err = p.Kind(name); err != nil {
+			return err
+		}
+	} else if flagAttach {
+		kind = config.TargetAttach
+	}
+	switch kind {
+	case config.TargetAttach:
+		var c config.AttachConfig
+		if err := config.InteractTarget(fp, name, kind, &c); err != nil {
+			return err
+		}
+		return runAttach(c)
+	default:
+		var c config.PatchConfig
+		if err := config.InteractTarget(fp, name, kind, &c); err != nil {
+			return err
+		}
+		return runPatch(baseDir, c)
+	}
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/foomo/gograpple/internal/config"
+	"github.com/foomo/gograpple/internal/kubectl"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	profileCmd.Flags().DurationVar(&flagDuration, "duration", 30*time.Second, "duration of cpu and trace profiles")
+	profileCmd.Flags().BoolVar(&flagOpen, "open", false, "open the first profile with go tool pprof -http")
+	profileCmd.Flags().BoolVar(&flagAttach, "attach", false, "profile the attach target (default is the patch target)")
+	profileCmd.Flags().StringVar(&flagOutDir, "out", "", "directory to write the profiles to (default gograpple-profile-<timestamp>)")
+	profileCmd.Flags().StringVar(&flagSaveDir, "save", ".", "directory of the saved configuration")
+	rootCmd.AddCommand(profileCmd)
+}
+
+var (
+	flagDuration time.Duration
+	flagOpen     bool
+	profileCmd   = &cobra.Command{
+		Use:         "profile [cpu|heap|goroutine|allocs|block|mutex|threadcreate|trace...]",
+		Short:       "collect pprof profiles from the patched or attached process",
+		Long:        "collect pprof profiles through a port-forward to the pprof port of the process (default: cpu heap goroutine)",
+		Annotations: sessionAnnotations,
+		ValidArgs:   []string{"cpu", "heap", "goroutine", "allocs", "block", "mutex", "threadcreate", "trace"},
+		Args:        cobra.OnlyValidArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				args = []string{"cpu", "heap", "goroutine"}
+			}
+			namespace, deployment, container, port, err := profileTarget()
+			if err != nil {
+				return err
+			}
+			g, err := newGrapple(namespace, deployment)
+			if err != nil {
+				return err
+			}
+			outDir := flagOutDir
+			if outDir == "" {
+				outDir = fmt.Sprintf("gograpple-profile-%v", time.Now().Format("20060102-150405"))
+			}
+			return g.Profile("", container, port, args, flagDuration, outDir, flagOpen)
+		},
+	}
+)
+
+// profileTarget loads the saved attach or patch configuration and sets its cluster
+func profileTarget() (namespace, deployment, container string, port int, err error) {
+	if flagAttach {
+		var c config.AttachConfig
+		if err := config.Load(path.Join(flagSaveDir, "gograpple-attach.yaml"), &c); err != nil {
+			return "", "", "", 0, err
+		}
+		return c.Namespace, c.Deployment, c.Container, pprofPort(c.PprofPort), kubectl.SetContext(c.Cluster)
+	}
+	var c config.PatchConfig
+	if err := config.Load(path.Join(flagSaveDir, "gograpple-patch.yaml"), &c); err != nil {
+		return "", "", "", 0, err
+	}
+	return c.Namespace, c.Deployment, c.Container, pprofPort(c.PprofPort), kubectl.SetContext(c.Cluster)
+}
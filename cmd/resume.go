@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/foomo/gograpple/internal/config"
+	"github.com/foomo/gograpple/internal/grapple"
+	"github.com/foomo/gograpple/internal/kubectl"
+	"github.com/foomo/gograpple/internal/lifecycle"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	resumeCmd.Flags().StringVar(&flagSaveDir, "save", ".", "directory of the saved configuration")
+	rootCmd.AddCommand(resumeCmd)
+}
+
+var (
+	resumeCmd = &cobra.Command{
+		Use:         "resume",
+		Short:       "resume the debug session of a patched deployment, restarting dlv and the port-forward without patching",
+		Annotations: sessionAnnotations,
+		Args:        cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return resumeDebug(flagSaveDir)
+		},
+	}
+)
+
+func resumeDebug(baseDir string) error {
+	var c config.PatchConfig
+	if err := config.Load(path.Join(baseDir, "gograpple-patch.yaml"), &c); err != nil {
+		return err
+	}
+	if err := kubectl.SetContext(c.Cluster); err != nil {
+		return err
+	}
+	g, err := newGrapple(c.Namespace, c.Deployment)
+	if err != nil {
+		return err
+	}
+	g.Cluster(c.Cluster)
+	s, err := g.FindSession(context.Background())
+	if err != nil {
+		return err
+	}
+	g.Resume(s)
+	host, port, err := c.Addr()
+	if err != nil {
+		return err
+	}
+	env, err := c.Environ(baseDir)
+	if err != nil {
+		return err
+	}
+	mounts, err := grapple.ValidateMounts(baseDir, c.Mounts)
+	if err != nil {
+		return err
+	}
+	newLogEntry(flagDebug).Infof("resuming session on pod %v from %v", s.Pod, s.UpdatedAt.Format("2006-01-02 15:04:05"))
+	lifecycle.Register("rollback deployment", 2*time.Minute, g.Undo)
+	return runDebug(g, c, mounts, env, host, port)
+}
@@ -1,7 +1,8 @@
 package cmd
 
 import (
-	"github.com/foomo/gograpple/internal/grapple"
+	"context"
+
 	"github.com/spf13/cobra"
 )
 
@@ -15,11 +16,11 @@ var (
 		Short: "rollback the patched deployment",
 		Args:  cobra.MinimumNArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			g, err := grapple.NewGrapple(newLogEntry(flagDebug), args[0], args[1])
+			g, err := newGrapple(args[0], args[1])
 			if err != nil {
 				return err
 			}
-			return g.Rollback()
+			return g.Rollback(context.Background())
 		},
 	}
 )
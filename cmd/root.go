@@ -1,18 +1,36 @@
 package cmd
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/foomo/gograpple/internal/lifecycle"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&flagDebug, "debug", "", false, "debug mode")
+	rootCmd.PersistentFlags().StringVar(&flagLogFormat, "log-format", logFormatText, "log format, text or json (json includes executed commands)")
+	rootCmd.PersistentFlags().StringVar(&flagLogFile, "log-file", "", "also write the log of this session to a file")
+	rootCmd.PersistentFlags().BoolVar(&flagKeepWorkdir, "keep-workdir", false, "keep the work dir with rendered templates, build output and log of this session")
 }
 
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
 var (
 	// flagImage      string
 	// flagDir        string
-	flagDebug bool
+	flagDebug     bool
+	flagLogFormat string
+	flagLogFile   string
 	// flagNamespace  string
 	// flagPod        string
 	// flagContainer  string
@@ -24,27 +42,108 @@ var (
 	// flagListen     = NewHostPort("127.0.0.1", 0)
 	// flagVscode     bool
 	// flagContinue   bool
-	// flagJSONLog    bool
 	// flagDebug bool
 )
 
 var (
 	rootCmd = &cobra.Command{
 		Use: "gograpple",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := createWorkDir(cmd); err != nil {
+				return err
+			}
+			if err := configureLogger(logrus.StandardLogger(), flagDebug); err != nil {
+				return err
+			}
+			lifecycle.Listen(newLogEntry(flagDebug).WithField("component", "lifecycle"))
+			return nil
+		},
 	}
+	// sessionID is added to every log entry of this run
+	sessionID = newSessionID()
+	logFile   *os.File
 )
 
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		le := newLogEntry(flagDebug)
+	le := newLogEntry(flagDebug)
+	defer func() {
+		// clean up before crashing, panics in other goroutines cant be recovered
+		if r := recover(); r != nil {
+			lifecycle.Cleanup(le.WithField("component", "lifecycle"))
+			panic(r)
+		}
+	}()
+	err := rootCmd.Execute()
+	// the logger is configured by now
+	le = newLogEntry(flagDebug)
+	lifecycle.Cleanup(le.WithField("component", "lifecycle"))
+	if err != nil {
 		le.Fatal(err)
 	}
 }
 
+func newSessionID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
 func newLogEntry(debug bool) *logrus.Entry {
 	logger := logrus.New()
+	if err := configureLogger(logger, debug); err != nil {
+		logger.WithError(err).Warn("couldnt configure logger")
+	}
+	return logrus.NewEntry(logger)
+}
+
+// sessionHook adds the session id to every entry, including those of the standard logger
+type sessionHook struct{}
+
+func (sessionHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (sessionHook) Fire(e *logrus.Entry) error {
+	e.Data["session"] = sessionID
+	return nil
+}
+
+// configureLogger applies the log format and file flags, executed commands are logged on debug level,
+// which is enabled for json so wrappers can follow them
+func configureLogger(logger *logrus.Logger, debug bool) error {
+	switch flagLogFormat {
+	case logFormatText:
+	case logFormatJSON:
+		logger.SetFormatter(&logrus.JSONFormatter{})
+		logger.SetLevel(logrus.DebugLevel)
+	default:
+		return fmt.Errorf("invalid log format %q, expected %q or %q", flagLogFormat, logFormatText, logFormatJSON)
+	}
 	if debug {
 		logger.SetLevel(logrus.TraceLevel)
 	}
-	return logrus.NewEntry(logger)
+	logger.ReplaceHooks(logrus.LevelHooks{})
+	logger.AddHook(sessionHook{})
+	writers := []io.Writer{os.Stderr}
+	if flagLogFile != "" {
+		if logFile == nil {
+			p := flagLogFile
+			if fi, err := os.Stat(p); err == nil && fi.IsDir() {
+				p = filepath.Join(p, fmt.Sprintf("gograpple-%v.log", sessionID))
+			}
+			f, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				return err
+			}
+			logFile = f
+		}
+		writers = append(writers, logFile)
+	}
+	if workDirLog != nil {
+		writers = append(writers, workDirLogWriter{})
+	}
+	logger.SetOutput(io.MultiWriter(writers...))
+	return nil
 }
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"time"
+
+	"github.com/foomo/gograpple/internal/config"
+	"github.com/foomo/gograpple/internal/kubectl"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	snapshotCmd.Flags().BoolVar(&flagCore, "core", false, "also dump a core file")
+	snapshotCmd.Flags().StringVar(&flagOutDir, "out", "", "directory to write the snapshot to (default gograpple-snapshot-<timestamp>)")
+	snapshotCmd.Flags().StringVar(&flagSaveDir, "save", ".", "directory of the saved attach configuration")
+	rootCmd.AddCommand(snapshotCmd)
+}
+
+var (
+	flagCore    bool
+	flagOutDir  string
+	snapshotCmd = &cobra.Command{
+		Use:         "snapshot",
+		Short:       "capture goroutine stacks and optionally a core dump of the attach target",
+		Annotations: sessionAnnotations,
+		Args:        cobra.MinimumNArgs(0),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var c config.AttachConfig
+			if err := config.Load(path.Join(flagSaveDir, "gograpple-attach.yaml"), &c); err != nil {
+				return err
+			}
+			if err := kubectl.SetContext(c.Cluster); err != nil {
+				return err
+			}
+			g, err := newGrapple(c.Namespace, c.Deployment)
+			if err != nil {
+				return err
+			}
+			host, port, err := c.Addr()
+			if err != nil {
+				return err
+			}
+			outDir := flagOutDir
+			if outDir == "" {
+				outDir = fmt.Sprintf("gograpple-snapshot-%v", time.Now().Format("20060102-150405"))
+			}
+			return g.Snapshot(c.Namespace, c.Container, c.AttachTo, c.Arch, host, port, outDir, flagCore)
+		},
+	}
+)
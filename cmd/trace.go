@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/foomo/gograpple/internal/config"
+	"github.com/foomo/gograpple/internal/delve"
+	"github.com/foomo/gograpple/internal/grapple"
+	"github.com/foomo/gograpple/internal/lifecycle"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	traceCmd.Flags().StringSliceVarP(&flagExprs, "expr", "e", nil, "expressions to evaluate on every hit")
+	traceCmd.Flags().StringVar(&flagSaveDir, "save", ".", "directory of the saved configuration")
+	rootCmd.AddCommand(traceCmd)
+}
+
+var (
+	flagExprs []string
+	traceCmd  = &cobra.Command{
+		Use:   "trace [file:line|function...]",
+		Short: "stream tracepoint hits of a running debug session as json lines",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var c config.PatchConfig
+			if err := config.Load(path.Join(flagSaveDir, "gograpple-patch.yaml"), &c); err != nil {
+				return err
+			}
+			if protocol(c.Protocol) == delve.ProtocolDAP {
+				return fmt.Errorf("tracing needs the %q protocol", delve.ProtocolRPC)
+			}
+			host, port, err := sessionAddr(c)
+			if err != nil {
+				return err
+			}
+			ctx, stop := lifecycle.NotifyContext(context.Background())
+			defer stop()
+			return grapple.Trace(ctx, newLogEntry(flagDebug), c.SourcePath, host, port, c.TrimPath, args, flagExprs)
+		},
+	}
+)
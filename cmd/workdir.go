@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/foomo/gograpple/internal/grapple"
+	"github.com/foomo/gograpple/internal/lifecycle"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	workDirLogFile    = "gograpple.log"
+	workDirAnnotation = "workdir"
+)
+
+// sessionAnnotations mark the commands running a session, only they get a work dir
+var sessionAnnotations = map[string]string{workDirAnnotation: "true"}
+
+var (
+	flagKeepWorkdir bool
+	// workDir holds the files of this session, like rendered templates, build output and the log
+	workDir      string
+	workDirLog   *os.File
+	workDirLogMu sync.Mutex
+)
+
+// createWorkDir creates the work dir for commands annotated with sessionAnnotations
+func createWorkDir(cmd *cobra.Command) error {
+	if workDir != "" || cmd.Annotations[workDirAnnotation] == "" {
+		return nil
+	}
+	dir, err := os.MkdirTemp("", fmt.Sprintf("gograpple-%v-", sessionID))
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, workDirLogFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	workDir, workDirLog = dir, f
+	// registered first so its removed last
+	lifecycle.Register("remove workdir", 0, func(ctx context.Context) error {
+		return removeWorkDir()
+	})
+	return nil
+}
+
+// removeWorkDir removes the work dir on exit, unless it should be kept for troubleshooting
+func removeWorkDir() error {
+	if flagKeepWorkdir {
+		logrus.Infof("keeping workdir %v", workDir)
+		return nil
+	}
+	workDirLogMu.Lock()
+	_ = workDirLog.Close()
+	workDirLog = nil
+	workDirLogMu.Unlock()
+	return os.RemoveAll(workDir)
+}
+
+// workDirLogWriter writes to the log of the work dir until it is removed
+type workDirLogWriter struct{}
+
+func (workDirLogWriter) Write(p []byte) (int, error) {
+	workDirLogMu.Lock()
+	defer workDirLogMu.Unlock()
+	if workDirLog == nil {
+		return len(p), nil
+	}
+	return workDirLog.Write(p)
+}
+
+// newGrapple creates a grapple using the work dir of the session
+func newGrapple(namespace, deployment string) (*grapple.Grapple, error) {
+	g, err := grapple.NewGrapple(newLogEntry(flagDebug), namespace, deployment)
+	if err != nil {
+		return nil, err
+	}
+	g.WorkDir(workDir)
+	return g, nil
+}
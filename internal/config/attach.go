@@ -1,10 +1,8 @@
 package config
 
 import (
-	"fmt"
 	"os"
 	"path"
-	"strconv"
 	"strings"
 
 	"github.com/c-bata/go-prompt"
@@ -15,29 +13,25 @@ import (
 
 type AttachConfig struct {
 	SourcePath string `yaml:"source_path"`
-	Cluster    string `yaml:"cluster"`
-	Namespace  string `yaml:"namespace" depends:"Cluster"`
-	Deployment string `yaml:"deployment" depends:"Namespace"`
+	Cluster    string `yaml:"cluster" required:"true"`
+	Namespace  string `yaml:"namespace" depends:"Cluster" required:"true"`
+	Deployment string `yaml:"deployment" depends:"Namespace" required:"true"`
 	Container  string `yaml:"container" depends:"Deployment"`
 	ListenAddr string `yaml:"listen_addr,omitempty" default:"127.0.0.1:2345"`
+	Protocol   string `yaml:"protocol,omitempty" default:"rpc"`
 
-	AttachTo string `yaml:"attach_to" depends:"Container"`
-	Arch     string `yaml:"arch" default:"amd64"`
+	AttachTo  string `yaml:"attach_to" depends:"Container" required:"true"`
+	Arch      string `yaml:"arch" default:"amd64"`
+	BuildPath string `yaml:"build_path,omitempty"`
+	PprofPort int    `yaml:"pprof_port,omitempty" default:"6060"`
 }
 
 func (c AttachConfig) Addr() (host string, port int, err error) {
-	pieces := strings.Split(c.ListenAddr, ":")
-	if len(pieces) != 2 {
-		return host, port, fmt.Errorf("unable to parse addr from %q", c.ListenAddr)
-	}
-	host = pieces[0]
-	if host == "" {
-		host = "127.0.0.1"
-	}
-	if port, err = strconv.Atoi(pieces[1]); err != nil {
-		return host, port, err
-	}
-	return host, port, err
+	return parseAddr(c.ListenAddr)
+}
+
+func (c *AttachConfig) resolvePaths(dir string) {
+	c.SourcePath = resolvePath(dir, c.SourcePath)
 }
 
 func (c AttachConfig) MarshalYAML() (interface{}, error) {
@@ -86,7 +80,11 @@ func (c AttachConfig) ContainerSuggest(d prompt.Document) []prompt.Suggest {
 }
 
 func (c AttachConfig) ListenAddrSuggest(d prompt.Document) []prompt.Suggest {
-	return []prompt.Suggest{{Text: ":2345"}}
+	return []prompt.Suggest{{Text: ":2345"}, {Text: autoPort}}
+}
+
+func (c AttachConfig) ProtocolSuggest(d prompt.Document) []prompt.Suggest {
+	return []prompt.Suggest{{Text: "rpc"}, {Text: "dap"}}
 }
 
 func (c AttachConfig) AttachToSuggest(d prompt.Document) []prompt.Suggest {
@@ -110,3 +108,11 @@ func (c AttachConfig) AttachToSuggest(d prompt.Document) []prompt.Suggest {
 func (c AttachConfig) ArchSuggest(d prompt.Document) []prompt.Suggest {
 	return []prompt.Suggest{{Text: "amd64"}, {Text: "arm64"}}
 }
+
+func (c AttachConfig) BuildPathSuggest(d prompt.Document) []prompt.Suggest {
+	return []prompt.Suggest{{Text: "/"}}
+}
+
+func (c AttachConfig) PprofPortSuggest(d prompt.Document) []prompt.Suggest {
+	return []prompt.Suggest{{Text: "6060"}}
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/bitfield/script"
@@ -18,7 +20,37 @@ func init() {
 	log = logrus.NewEntry(logrus.StandardLogger())
 }
 
-const defaultImage = "alpine:latest"
+const (
+	defaultImage = "alpine:latest"
+	// autoPort lets gograpple pick free ports for the session
+	autoPort = "auto"
+)
+
+// migrator is implemented by configs that carry deprecated fields
+type migrator interface {
+	migrate()
+}
+
+// pathResolver is implemented by configs with paths relative to their config file
+type pathResolver interface {
+	resolvePaths(dir string)
+}
+
+// resolvePaths resolves the relative paths loaded from filePath against its directory,
+// prompted paths stay relative to the working dir
+func resolvePaths(filePath string, config interface{}) {
+	if r, ok := config.(pathResolver); ok {
+		r.resolvePaths(filepath.Dir(filePath))
+	}
+}
+
+// resolvePath joins a relative path p to dir
+func resolvePath(dir, p string) string {
+	if p == "" || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(dir, p)
+}
 
 // load the already existing config or enter interactive mode to generate one
 func Interact(filePath string, config interface{}) error {
@@ -27,10 +59,18 @@ func Interact(filePath string, config interface{}) error {
 	if filePath != "" {
 		configLoaded := false
 		if _, err := os.Stat(filePath); err == nil {
+			// missing values are prompted for
+			if err := validate(filePath, config, false); err != nil {
+				return err
+			}
 			if err := loadYaml(filePath, config); err != nil {
 				// if the config path doesnt exist
 				return err
 			}
+			resolvePaths(filePath, config)
+			if m, ok := config.(migrator); ok {
+				m.migrate()
+			}
 			configLoaded = true
 		}
 		if configLoaded {
@@ -38,12 +78,52 @@ func Interact(filePath string, config interface{}) error {
 			opts = append(opts, gencon.OptionSkipFilled())
 		}
 	}
-	// run configuration create with suggestions
+	if err := promptConfig(config, opts...); err != nil {
+		return err
+	}
+	return save(filePath, config)
+}
+
+// InteractTarget loads the target from the project file on top of its defaults and prompts for missing values,
+// the kind of an existing target is kept, new targets are appended to the file leaving the others untouched
+func InteractTarget(filePath, name, kind string, config interface{}) error {
+	defer handleConfigExit()
+	if _, err := os.Stat(filePath); err == nil {
+		if err := validateProject(filePath, false); err != nil {
+			return err
+		}
+	}
+	p, err := LoadProject(filePath)
+	if err != nil {
+		return err
+	}
+	if _, ok := p.Targets[name]; ok {
+		if kind, err = p.Kind(name); err != nil {
+			return err
+		}
+	} else {
+		log.Infof("adding target %q to %q", name, filePath)
+	}
+	if err := p.Target(name, config); err != nil {
+		return err
+	}
+	resolvePaths(filePath, config)
+	if err := promptConfig(config, gencon.OptionSkipFilled()); err != nil {
+		return err
+	}
+	if err := p.SetTarget(name, kind, config); err != nil {
+		return err
+	}
+	return p.Save(filePath)
+}
+
+// promptConfig runs the configuration create with suggestions
+func promptConfig(config interface{}, opts ...gencon.Option) error {
 	w, err := gencon.New(opts...)
 	if err != nil {
 		return err
 	}
-	if err := w.Prompt(config,
+	return w.Prompt(config,
 		prompt.OptionShowCompletionAtStart(),
 		prompt.OptionPrefixTextColor(prompt.Fuchsia),
 		// since we have a file completer
@@ -52,10 +132,22 @@ func Interact(filePath string, config interface{}) error {
 		prompt.OptionAddKeyBind(prompt.KeyBind{
 			Key: prompt.ControlC,
 			Fn:  promptExit,
-		})); err != nil {
+		}))
+}
+
+// Load validates and reads an existing config without prompting
+func Load(filePath string, config interface{}) error {
+	if err := Validate(filePath, config); err != nil {
 		return err
 	}
-	return save(filePath, config)
+	if err := loadYaml(filePath, config); err != nil {
+		return err
+	}
+	resolvePaths(filePath, config)
+	if m, ok := config.(migrator); ok {
+		m.migrate()
+	}
+	return nil
 }
 
 func save(path string, c interface{}) error {
@@ -104,3 +196,25 @@ func find(args ...string) ([]string, error) {
 func findContaining(v string, args ...string) ([]string, error) {
 	return script.Exec(fmt.Sprintf("find %v -exec grep -lr %q {} +", strings.Join(args, " "), v)).Slice()
 }
+
+// parseAddr parses host:port, a port of 0 or auto means the ports are allocated for the session
+func parseAddr(addr string) (host string, port int, err error) {
+	if addr == autoPort {
+		addr = ":" + autoPort
+	}
+	pieces := strings.Split(addr, ":")
+	if len(pieces) != 2 {
+		return host, port, fmt.Errorf("unable to parse addr from %q", addr)
+	}
+	host = pieces[0]
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if pieces[1] == autoPort {
+		return host, 0, nil
+	}
+	if port, err = strconv.Atoi(pieces[1]); err != nil {
+		return host, port, err
+	}
+	return host, port, err
+}
@@ -0,0 +1,56 @@
+package config
+
+import "testing"
+
+func Test_parseAddr(t *testing.T) {
+	tests := []struct {
+		addr     string
+		wantHost string
+		wantPort int
+		wantErr  bool
+	}{
+		{"127.0.0.1:2345", "127.0.0.1", 2345, false},
+		{":2345", "127.0.0.1", 2345, false},
+		{":0", "127.0.0.1", 0, false},
+		{"127.0.0.1:auto", "127.0.0.1", 0, false},
+		{"auto", "127.0.0.1", 0, false},
+		{"2345", "", 0, true},
+		{":port", "127.0.0.1", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			host, port, err := parseAddr(tt.addr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAddr() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if host != tt.wantHost || port != tt.wantPort {
+				t.Errorf("parseAddr() = %v, %v, want %v, %v", host, port, tt.wantHost, tt.wantPort)
+			}
+		})
+	}
+}
+
+func Test_resolvePaths(t *testing.T) {
+	tests := []struct {
+		name       string
+		sourcePath string
+		want       string
+	}{
+		{"relative", "cmd/app", "/project/config/cmd/app"},
+		{"parent", "../cmd/app/main.go", "/project/cmd/app/main.go"},
+		{"absolute", "/src/main.go", "/src/main.go"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &PatchConfig{SourcePath: tt.sourcePath}
+			resolvePaths("/project/config/gograpple-patch.yaml", c)
+			if c.SourcePath != tt.want {
+				t.Errorf("resolvePaths() source path = %q, want %q", c.SourcePath, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// readEnvFile parses KEY=value lines, empty lines, comments and an export prefix are skipped
+func readEnvFile(r io.Reader) (map[string]string, error) {
+	env := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid env line %d: %q", n, line)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) > 1 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env[key] = value
+	}
+	return env, scanner.Err()
+}
+
+// environ merges the env file with the env values, which take precedence, into sorted KEY=value pairs
+func environ(envFile string, values map[string]string) ([]string, error) {
+	env := map[string]string{}
+	if envFile != "" {
+		f, err := os.Open(envFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		if env, err = readEnvFile(f); err != nil {
+			return nil, fmt.Errorf("couldnt read env file %q: %w", envFile, err)
+		}
+	}
+	for key, value := range values {
+		env[key] = value
+	}
+	var pairs []string
+	for key, value := range env {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", key, value))
+	}
+	sort.Strings(pairs)
+	return pairs, nil
+}
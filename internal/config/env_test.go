@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_readEnvFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"plain", "A=1\nB=two words\n", map[string]string{"A": "1", "B": "two words"}, false},
+		{"comments and export", "# flags\n\nexport A=1\n", map[string]string{"A": "1"}, false},
+		{"quoted", "A=\"x=y\"\nB='z'\n", map[string]string{"A": "x=y", "B": "z"}, false},
+		{"empty value", "A=\n", map[string]string{"A": ""}, false},
+		{"missing separator", "A\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readEnvFile(strings.NewReader(tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readEnvFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readEnvFile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchConfig_Environ(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "debug.env"), []byte("A=1\nB=2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		envFile string
+	}{
+		{"relative to the config", "debug.env"},
+		{"absolute", filepath.Join(dir, "debug.env")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PatchConfig{EnvFile: tt.envFile, Env: map[string]string{"B": "3"}}.Environ(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"A=1", "B=3"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("Environ() = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -1,11 +1,8 @@
 package config
 
 import (
-	"fmt"
 	"os"
 	"path"
-	"strconv"
-	"strings"
 
 	"github.com/c-bata/go-prompt"
 	"github.com/foomo/gograpple/internal/kubectl"
@@ -13,32 +10,79 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+const (
+	argsModeReplace = "replace"
+	argsModeAppend  = "append"
+)
+
 type PatchConfig struct {
-	SourcePath string `yaml:"source_path"`
-	Cluster    string `yaml:"cluster"`
-	Namespace  string `yaml:"namespace" depends:"Cluster"`
-	Deployment string `yaml:"deployment" depends:"Namespace"`
+	SourcePath string `yaml:"source_path" required:"true"`
+	Cluster    string `yaml:"cluster" required:"true"`
+	Namespace  string `yaml:"namespace" depends:"Cluster" required:"true"`
+	Deployment string `yaml:"deployment" depends:"Namespace" required:"true"`
 	Container  string `yaml:"container" depends:"Deployment"`
 	ListenAddr string `yaml:"listen_addr,omitempty" default:"127.0.0.1:2345"`
+	Protocol   string `yaml:"protocol,omitempty" default:"rpc"`
 
 	Image         string `yaml:"image,omitempty" default:"alpine:latest"`
 	DelveContinue bool   `yaml:"delve_continue" default:"false"`
-	LaunchVscode  bool   `yaml:"launch_vscode" default:"false"`
+	StreamOutput  bool   `yaml:"stream_output" default:"false"`
+	IDE           string `yaml:"ide,omitempty" default:"none"`
+	Probes        string `yaml:"probes,omitempty" default:"drop"`
+	LaunchJSON    bool   `yaml:"launch_json" default:"false"`
+	RunConfig     bool   `yaml:"run_config" default:"false"`
+	TrimPath      bool   `yaml:"trim_path" default:"false"`
+	PprofPort     int    `yaml:"pprof_port,omitempty" default:"6060"`
+	PprofInject   bool   `yaml:"pprof_inject" default:"false"`
+
+	Args     []string          `yaml:"args,omitempty"`
+	ArgsMode string            `yaml:"args_mode,omitempty" default:"replace"`
+	Env      map[string]string `yaml:"env,omitempty"`
+	EnvFile  string            `yaml:"env_file,omitempty"`
+	Mounts   []string          `yaml:"mounts,omitempty"`
+
+	PatchTemplate string            `yaml:"patch_template,omitempty"`
+	Dockerfile    string            `yaml:"dockerfile,omitempty"`
+	Overlays      []PatchOverlay    `yaml:"overlays,omitempty"`
+	Vars          map[string]string `yaml:"vars,omitempty"`
+
+	// deprecated: replaced by IDE
+	LaunchVscode *bool `yaml:"launch_vscode,omitempty"`
 }
 
-func (c PatchConfig) Addr() (host string, port int, err error) {
-	pieces := strings.Split(c.ListenAddr, ":")
-	if len(pieces) != 2 {
-		return host, port, fmt.Errorf("unable to parse addr from %q", c.ListenAddr)
-	}
-	host = pieces[0]
-	if host == "" {
-		host = "127.0.0.1"
-	}
-	if port, err = strconv.Atoi(pieces[1]); err != nil {
-		return host, port, err
+// PatchOverlay is an additive json or strategic merge patch applied on top of the deployment patch
+type PatchOverlay struct {
+	Type  string `yaml:"type"`
+	Patch string `yaml:"patch"`
+}
+
+func (c *PatchConfig) migrate() {
+	if c.LaunchVscode != nil && c.IDE == "" {
+		c.IDE = "none"
+		if *c.LaunchVscode {
+			c.IDE = "vscode"
+		}
 	}
-	return host, port, err
+	c.LaunchVscode = nil
+}
+
+func (c PatchConfig) Addr() (host string, port int, err error) {
+	return parseAddr(c.ListenAddr)
+}
+
+// Environ returns the env for the debug run from env_file and env, env takes precedence,
+// a relative env_file is resolved from baseDir
+func (c PatchConfig) Environ(baseDir string) ([]string, error) {
+	return environ(resolvePath(baseDir, c.EnvFile), c.Env)
+}
+
+func (c *PatchConfig) resolvePaths(dir string) {
+	c.SourcePath = resolvePath(dir, c.SourcePath)
+}
+
+// AppendArgs is true if args are appended to the original args instead of replacing them
+func (c PatchConfig) AppendArgs() bool {
+	return c.ArgsMode == argsModeAppend
 }
 
 func (c PatchConfig) MarshalYAML() (interface{}, error) {
@@ -87,7 +131,11 @@ func (c PatchConfig) ContainerSuggest(d prompt.Document) []prompt.Suggest {
 }
 
 func (c PatchConfig) ListenAddrSuggest(d prompt.Document) []prompt.Suggest {
-	return []prompt.Suggest{{Text: ":2345"}}
+	return []prompt.Suggest{{Text: ":2345"}, {Text: autoPort}}
+}
+
+func (c PatchConfig) ProtocolSuggest(d prompt.Document) []prompt.Suggest {
+	return []prompt.Suggest{{Text: "rpc"}, {Text: "dap"}}
 }
 
 func (c PatchConfig) ImageSuggest(d prompt.Document) []prompt.Suggest {
@@ -101,6 +149,44 @@ func (c PatchConfig) DelveContinueSuggest(d prompt.Document) []prompt.Suggest {
 	return []prompt.Suggest{{Text: "true"}, {Text: "false"}}
 }
 
-func (c PatchConfig) LaunchVscodeSuggest(d prompt.Document) []prompt.Suggest {
+func (c PatchConfig) StreamOutputSuggest(d prompt.Document) []prompt.Suggest {
+	return []prompt.Suggest{{Text: "true"}, {Text: "false"}}
+}
+
+func (c PatchConfig) IDESuggest(d prompt.Document) []prompt.Suggest {
+	return []prompt.Suggest{{Text: "none"}, {Text: "vscode"}, {Text: "goland"}}
+}
+
+func (c PatchConfig) ProbesSuggest(d prompt.Document) []prompt.Suggest {
+	return []prompt.Suggest{{Text: "drop"}, {Text: "proxy"}, {Text: "breakpoint"}}
+}
+
+func (c PatchConfig) LaunchJSONSuggest(d prompt.Document) []prompt.Suggest {
+	return []prompt.Suggest{{Text: "true"}, {Text: "false"}}
+}
+
+func (c PatchConfig) RunConfigSuggest(d prompt.Document) []prompt.Suggest {
+	return []prompt.Suggest{{Text: "true"}, {Text: "false"}}
+}
+
+func (c PatchConfig) TrimPathSuggest(d prompt.Document) []prompt.Suggest {
 	return []prompt.Suggest{{Text: "true"}, {Text: "false"}}
 }
+
+func (c PatchConfig) PprofPortSuggest(d prompt.Document) []prompt.Suggest {
+	return []prompt.Suggest{{Text: "6060"}}
+}
+
+func (c PatchConfig) PprofInjectSuggest(d prompt.Document) []prompt.Suggest {
+	return []prompt.Suggest{{Text: "true"}, {Text: "false"}}
+}
+
+func (c PatchConfig) ArgsModeSuggest(d prompt.Document) []prompt.Suggest {
+	return []prompt.Suggest{{Text: argsModeReplace}, {Text: argsModeAppend}}
+}
+
+func (c PatchConfig) EnvFileSuggest(d prompt.Document) []prompt.Suggest {
+	return suggest.Completer(d, suggest.MustList(func() ([]string, error) {
+		return findContaining("=", ".", "-type", "f", "-name", "*.env")
+	}))
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ProjectFile holds the named targets of a project
+	ProjectFile = "gograpple.yaml"
+
+	TargetPatch  = "patch"
+	TargetAttach = "attach"
+
+	targetKindKey = "kind"
+)
+
+// Project holds named targets, each one a PatchConfig or AttachConfig picked by its kind,
+// targets are decoded on top of the shared defaults and only keep their overrides when saved
+type Project struct {
+	Defaults yaml.Node            `yaml:"defaults,omitempty"`
+	Targets  map[string]yaml.Node `yaml:"targets"`
+}
+
+// LoadProject reads the project file, a missing file is an empty project
+func LoadProject(filePath string) (*Project, error) {
+	p := &Project{}
+	if err := loadYaml(filePath, p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if p.Targets == nil {
+		p.Targets = map[string]yaml.Node{}
+	}
+	return p, nil
+}
+
+// TargetNames returns the sorted names of the targets
+func (p Project) TargetNames() []string {
+	var names []string
+	for name := range p.Targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Kind returns TargetPatch or TargetAttach for the target, patch is the default
+func (p Project) Kind(name string) (string, error) {
+	node, ok := p.Targets[name]
+	if !ok {
+		return "", fmt.Errorf("target %q not found, expected one of %q", name, p.TargetNames())
+	}
+	var t struct {
+		Kind string `yaml:"kind"`
+	}
+	if err := node.Decode(&t); err != nil {
+		return "", err
+	}
+	switch t.Kind {
+	case "", TargetPatch:
+		return TargetPatch, nil
+	case TargetAttach:
+		return TargetAttach, nil
+	}
+	return "", fmt.Errorf("target %q has invalid kind %q, expected %q or %q", name, t.Kind, TargetPatch, TargetAttach)
+}
+
+// Target decodes the defaults and the overrides of the target into config,
+// only the defaults are decoded for a target that doesnt exist yet
+func (p Project) Target(name string, config interface{}) error {
+	if !p.Defaults.IsZero() {
+		if err := p.Defaults.Decode(config); err != nil {
+			return fmt.Errorf("couldnt decode defaults: %w", err)
+		}
+	}
+	if node, ok := p.Targets[name]; ok {
+		if err := node.Decode(config); err != nil {
+			return fmt.Errorf("couldnt decode target %q: %w", name, err)
+		}
+	}
+	if m, ok := config.(migrator); ok {
+		m.migrate()
+	}
+	return nil
+}
+
+// SetTarget stores config as target name of the given kind, keeping only the values that differ from the defaults
+func (p *Project) SetTarget(name, kind string, config interface{}) error {
+	var node yaml.Node
+	if err := node.Encode(config); err != nil {
+		return err
+	}
+	if node.Kind != yaml.MappingNode {
+		return fmt.Errorf("target %q must be a map", name)
+	}
+	defaults := map[string]*yaml.Node{}
+	if p.Defaults.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(p.Defaults.Content); i += 2 {
+			defaults[p.Defaults.Content[i].Value] = p.Defaults.Content[i+1]
+		}
+	}
+	overrides := []*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: targetKindKey},
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: kind},
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if d, ok := defaults[key.Value]; ok && equalNodes(d, value) {
+			continue
+		}
+		overrides = append(overrides, key, value)
+	}
+	node.Content = overrides
+	p.Targets[name] = node
+	return nil
+}
+
+// Save writes the project file
+func (p Project) Save(filePath string) error {
+	return save(filePath, p)
+}
+
+func equalNodes(a, b *yaml.Node) bool {
+	ab, err := yaml.Marshal(a)
+	if err != nil {
+		return false
+	}
+	bb, err := yaml.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(ab, bb)
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProject_targets(t *testing.T) {
+	fp := filepath.Join(t.TempDir(), ProjectFile)
+	p, err := LoadProject(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Defaults.Encode(map[string]interface{}{
+		"cluster": "dev", "namespace": "shop", "image": "alpine:latest", "env": map[string]string{"LOG": "debug"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	search := PatchConfig{Cluster: "dev", Namespace: "shop", Deployment: "search", Image: "alpine:latest", Env: map[string]string{"LOG": "debug"}}
+	if err := p.SetTarget("search", TargetPatch, search); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SetTarget("checkout", TargetAttach, AttachConfig{Cluster: "dev", Namespace: "payments", Deployment: "checkout"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Save(fp); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err = LoadProject(fp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := p.TargetNames(), []string{"checkout", "search"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("TargetNames() = %v, want %v", got, want)
+	}
+	var overrides map[string]interface{}
+	node := p.Targets["search"]
+	if err := node.Decode(&overrides); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"cluster", "namespace", "image", "env"} {
+		if _, ok := overrides[key]; ok {
+			t.Errorf("target search stores default %q", key)
+		}
+	}
+	var got PatchConfig
+	if err := p.Target("search", &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Cluster != "dev" || got.Namespace != "shop" || got.Deployment != "search" || got.Env["LOG"] != "debug" {
+		t.Errorf("Target() = %+v, want defaults with overrides", got)
+	}
+	var attach AttachConfig
+	if err := p.Target("checkout", &attach); err != nil {
+		t.Fatal(err)
+	}
+	if attach.Cluster != "dev" || attach.Namespace != "payments" {
+		t.Errorf("Target() = %+v, want namespace override", attach)
+	}
+	for name, want := range map[string]string{"search": TargetPatch, "checkout": TargetAttach} {
+		if kind, err := p.Kind(name); err != nil || kind != want {
+			t.Errorf("Kind(%q) = %v, %v, want %v", name, kind, err, want)
+		}
+	}
+	if _, err := p.Kind("missing"); err == nil {
+		t.Error("Kind() of a missing target, want error")
+	}
+}
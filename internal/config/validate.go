@@ -0,0 +1,452 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/foomo/gograpple/internal/kubectl"
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is an invalid value or key at a position of a config file
+type Problem struct {
+	File   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%v:%v:%v: %v", p.File, p.Line, p.Column, p.Msg)
+}
+
+// ValidationError lists the problems of a config file, sorted by position
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = p.String()
+	}
+	return fmt.Sprintf("invalid config:\n%v", strings.Join(lines, "\n"))
+}
+
+// Validate checks the config file for config, a PatchConfig or AttachConfig, without decoding it
+func Validate(filePath string, config interface{}) error {
+	return validate(filePath, config, true)
+}
+
+// ValidateProject checks the defaults and every target of the project file
+func ValidateProject(filePath string) error {
+	return validateProject(filePath, true)
+}
+
+// ValidateFile checks a config file, the project file is picked by its name, other files containing attach
+// hold an AttachConfig and a PatchConfig otherwise
+func ValidateFile(filePath string) error {
+	switch name := filepath.Base(filePath); {
+	case name == ProjectFile:
+		return ValidateProject(filePath)
+	case strings.Contains(name, TargetAttach):
+		return Validate(filePath, &AttachConfig{})
+	default:
+		return Validate(filePath, &PatchConfig{})
+	}
+}
+
+// validate skips the required fields when they are prompted for
+func validate(filePath string, config interface{}, required bool) error {
+	return newValidator(filePath, required).file(func(v *validator, doc *yaml.Node) {
+		v.config(doc, nil, reflect.TypeOf(config))
+	})
+}
+
+func validateProject(filePath string, required bool) error {
+	return newValidator(filePath, required).file((*validator).project)
+}
+
+type validator struct {
+	path     string
+	required bool
+	contexts func() ([]string, error)
+	problems []Problem
+
+	// listed once per file
+	listed      bool
+	clusters    []string
+	clustersErr error
+}
+
+func newValidator(path string, required bool) *validator {
+	return &validator{path: path, required: required, contexts: kubectl.ListContexts}
+}
+
+// file parses the config file and collects the problems of check
+func (v *validator) file(check func(v *validator, doc *yaml.Node)) error {
+	bs, err := os.ReadFile(v.path)
+	if err != nil {
+		return err
+	}
+	return v.bytes(bs, check)
+}
+
+func (v *validator) bytes(bs []byte, check func(v *validator, doc *yaml.Node)) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(bs, &doc); err != nil {
+		msg := strings.TrimPrefix(err.Error(), "yaml: ")
+		v.problems = append(v.problems, Problem{v.path, errorLine(msg), 1, lineRegexp.ReplaceAllString(msg, "")})
+		return v.err()
+	}
+	if len(doc.Content) == 0 {
+		v.add(&doc, "empty config")
+		return v.err()
+	}
+	check(v, doc.Content[0])
+	return v.err()
+}
+
+func (v *validator) err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		a, b := v.problems[i], v.problems[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return &ValidationError{Problems: v.problems}
+}
+
+func (v *validator) add(n *yaml.Node, format string, args ...interface{}) {
+	line, column := n.Line, n.Column
+	if line == 0 {
+		line, column = 1, 1
+	}
+	v.problems = append(v.problems, Problem{v.path, line, column, fmt.Sprintf(format, args...)})
+}
+
+// project checks the keys of defaults against all kinds and each target merged with the defaults
+func (v *validator) project(n *yaml.Node) {
+	if !v.mapping(n, "project") {
+		return
+	}
+	var defaults *yaml.Node
+	for _, kv := range pairs(n) {
+		switch kv[0].Value {
+		case "defaults":
+			if v.mapping(kv[1], "defaults") {
+				defaults = kv[1]
+				v.fields(defaults, nil, reflect.TypeOf(PatchConfig{}), reflect.TypeOf(AttachConfig{}))
+			}
+		case "targets":
+			if isNull(kv[1]) {
+				continue
+			}
+			if !v.mapping(kv[1], "targets") {
+				continue
+			}
+			for _, target := range pairs(kv[1]) {
+				v.target(target[0].Value, target[1], defaults)
+			}
+		default:
+			v.unknown(kv[0], []string{"defaults", "targets"})
+		}
+	}
+}
+
+func (v *validator) target(name string, n, defaults *yaml.Node) {
+	if !v.mapping(n, fmt.Sprintf("target %q", name)) {
+		return
+	}
+	var config interface{} = PatchConfig{}
+	if kind := lookup(n, targetKindKey); kind != nil {
+		switch kind.Value {
+		case TargetPatch:
+		case TargetAttach:
+			config = AttachConfig{}
+		default:
+			v.add(kind, "target %q has invalid kind %q, expected %q or %q", name, kind.Value, TargetPatch, TargetAttach)
+			return
+		}
+	}
+	v.config(n, defaults, reflect.TypeOf(config), targetKindKey)
+}
+
+// config checks the keys of n and the values of n merged on top of defaults
+func (v *validator) config(n, defaults *yaml.Node, t reflect.Type, allowed ...string) {
+	if !v.mapping(n, "config") {
+		return
+	}
+	v.fields(n, allowed, t)
+	values := map[string]*yaml.Node{}
+	for _, m := range []*yaml.Node{defaults, n} {
+		if m == nil {
+			continue
+		}
+		for _, kv := range pairs(m) {
+			values[kv[0].Value] = kv[1]
+		}
+	}
+	t = elem(t)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		key := yamlKey(f)
+		value, ok := values[key]
+		if !ok || isNull(value) || value.Value == "" && value.Kind == yaml.ScalarNode {
+			if v.required && f.Tag.Get("required") == "true" {
+				if ok {
+					v.add(value, "%v must not be empty", key)
+				} else {
+					v.add(n, "missing required key %v", key)
+				}
+			}
+			continue
+		}
+		if value.Kind != yaml.ScalarNode {
+			continue
+		}
+		switch key {
+		case "source_path":
+			v.sourcePath(value)
+		case "listen_addr":
+			if _, port, err := parseAddr(value.Value); err != nil || port < 0 || port > 65535 {
+				v.add(value, "invalid listen_addr %q, expected host:port, :port or %v", value.Value, autoPort)
+			}
+		case "cluster":
+			v.cluster(value)
+		case "args_mode":
+			if value.Value != argsModeReplace && value.Value != argsModeAppend {
+				v.add(value, "invalid args_mode %q, expected %q or %q", value.Value, argsModeReplace, argsModeAppend)
+			}
+		}
+	}
+}
+
+// fields reports unknown keys of n and values that dont decode into the field of the first type knowing the key
+func (v *validator) fields(n *yaml.Node, allowed []string, types ...reflect.Type) {
+	known := map[string]reflect.StructField{}
+	var keys []string
+	for _, t := range types {
+		t = elem(t)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			key := yamlKey(f)
+			if key == "" {
+				continue
+			}
+			if _, ok := known[key]; !ok {
+				known[key] = f
+				keys = append(keys, key)
+			}
+		}
+	}
+	for _, kv := range pairs(n) {
+		key, value := kv[0], kv[1]
+		if contains(allowed, key.Value) {
+			continue
+		}
+		f, ok := known[key.Value]
+		if !ok {
+			v.unknown(key, keys)
+			continue
+		}
+		v.value(value, f.Type)
+	}
+}
+
+// value checks that n decodes into t, descending into structs and lists of structs
+func (v *validator) value(n *yaml.Node, t reflect.Type) {
+	switch {
+	case elem(t).Kind() == reflect.Struct && n.Kind == yaml.MappingNode:
+		v.fields(n, nil, t)
+		return
+	case t.Kind() == reflect.Slice && elem(t.Elem()).Kind() == reflect.Struct && n.Kind == yaml.SequenceNode:
+		for _, item := range n.Content {
+			v.value(item, t.Elem())
+		}
+		return
+	}
+	if err := n.Decode(reflect.New(t).Interface()); err != nil {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+			err = errors.New(lineRegexp.ReplaceAllString(typeErr.Errors[0], ""))
+		}
+		v.add(n, "%v", err)
+	}
+}
+
+func (v *validator) unknown(key *yaml.Node, keys []string) {
+	if suggestion := closest(key.Value, keys); suggestion != "" {
+		v.add(key, "unknown key %v, did you mean %v?", key.Value, suggestion)
+		return
+	}
+	v.add(key, "unknown key %v", key.Value)
+}
+
+func (v *validator) mapping(n *yaml.Node, name string) bool {
+	if n.Kind != yaml.MappingNode {
+		v.add(n, "%v must be a map", name)
+		return false
+	}
+	return true
+}
+
+// sourcePath checks that the file or the go files of the directory are package main,
+// a relative path is resolved against the directory of the config file
+func (v *validator) sourcePath(n *yaml.Node) {
+	p := resolvePath(filepath.Dir(v.path), n.Value)
+	info, err := os.Stat(p)
+	if err != nil {
+		v.add(n, "source_path %q doesnt exist", n.Value)
+		return
+	}
+	fset := token.NewFileSet()
+	if !info.IsDir() {
+		f, err := parser.ParseFile(fset, p, nil, parser.PackageClauseOnly)
+		if err != nil {
+			v.add(n, "source_path %q is not a go file: %v", n.Value, err)
+		} else if f.Name.Name != "main" {
+			v.add(n, "source_path %q is package %v, expected package main", n.Value, f.Name.Name)
+		}
+		return
+	}
+	pkgs, err := parser.ParseDir(fset, p, func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, parser.PackageClauseOnly)
+	if err != nil {
+		v.add(n, "source_path %q: %v", n.Value, err)
+		return
+	}
+	if _, ok := pkgs["main"]; !ok {
+		v.add(n, "source_path %q doesnt contain package main", n.Value)
+	}
+}
+
+func (v *validator) cluster(n *yaml.Node) {
+	if !v.listed {
+		v.listed = true
+		v.clusters, v.clustersErr = v.contexts()
+		if v.clustersErr != nil {
+			log.WithError(v.clustersErr).Warn("couldnt list the cluster contexts, skipping their validation")
+		}
+	}
+	if v.clustersErr == nil && !contains(v.clusters, n.Value) {
+		v.add(n, "cluster %q is not a context of the kubeconfig, expected one of %q", n.Value, v.clusters)
+	}
+}
+
+// pairs returns the key and value nodes of a mapping
+func pairs(n *yaml.Node) [][2]*yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	var kvs [][2]*yaml.Node
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		kvs = append(kvs, [2]*yaml.Node{n.Content[i], n.Content[i+1]})
+	}
+	return kvs
+}
+
+func lookup(n *yaml.Node, key string) *yaml.Node {
+	for _, kv := range pairs(n) {
+		if kv[0].Value == key {
+			return kv[1]
+		}
+	}
+	return nil
+}
+
+func isNull(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.Tag == "!!null"
+}
+
+func yamlKey(f reflect.StructField) string {
+	if key := strings.Split(f.Tag.Get("yaml"), ",")[0]; key != "-" {
+		return key
+	}
+	return ""
+}
+
+func elem(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+var lineRegexp = regexp.MustCompile(`^line \d+: `)
+
+// errorLine returns the line of a yaml syntax error
+func errorLine(msg string) int {
+	var line int
+	if i := strings.Index(msg, "line "); i >= 0 {
+		fmt.Sscanf(msg[i:], "line %d", &line)
+	}
+	if line == 0 {
+		return 1
+	}
+	return line
+}
+
+// closest returns the key with the smallest edit distance of at most 2
+func closest(key string, keys []string) string {
+	best, bestDistance := "", 3
+	for _, k := range keys {
+		if d := distance(key, k); d < bestDistance {
+			best, bestDistance = k, d
+		}
+	}
+	return best
+}
+
+// distance is the levenshtein distance of a and b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minOf(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func minOf(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func Test_validator(t *testing.T) {
+	dir := t.TempDir()
+	mainFile := filepath.Join(dir, "main.go")
+	libFile := filepath.Join(dir, "lib.go")
+	if err := os.WriteFile(mainFile, []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(libFile, []byte("package lib\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	patch := func(v *validator, doc *yaml.Node) {
+		v.config(doc, nil, reflect.TypeOf(PatchConfig{}))
+	}
+	tests := []struct {
+		name  string
+		yaml  string
+		check func(v *validator, doc *yaml.Node)
+		want  []string
+	}{
+		{"valid", "source_path: " + mainFile + "\ncluster: dev\nnamespace: shop\ndeployment: search\nlisten_addr: auto\n", patch, nil},
+		{"unknown key", "source_path: " + mainFile + "\ncluster: dev\nnamespace: shop\ndeployment: search\nnamspace: shop\n", patch, []string{
+			"f.yaml:5:1: unknown key namspace, did you mean namespace?",
+		}},
+		{"required", "cluster: dev\nnamespace: \"\"\n", patch, []string{
+			"f.yaml:1:1: missing required key source_path",
+			"f.yaml:1:1: missing required key deployment",
+			"f.yaml:2:12: namespace must not be empty",
+		}},
+		{"values", "source_path: " + libFile + "\ncluster: prod\nnamespace: shop\ndeployment: search\nlisten_addr: 127.0.0.1\npprof_port: http\n", patch, []string{
+			"f.yaml:1:14: source_path \"" + libFile + "\" is package lib, expected package main",
+			"f.yaml:2:10: cluster \"prod\" is not a context of the kubeconfig, expected one of [\"dev\"]",
+			"f.yaml:5:14: invalid listen_addr \"127.0.0.1\", expected host:port, :port or auto",
+			"f.yaml:6:13: cannot unmarshal !!str `http` into int",
+		}},
+		{"relative to the config", "source_path: ../main.go\ncluster: dev\nnamespace: shop\ndeployment: search\nargs_mode: append\n", patch, nil},
+		{"args_mode", "source_path: ../main.go\ncluster: dev\nnamespace: shop\ndeployment: search\nargs_mode: prepend\n", patch, []string{
+			"f.yaml:5:12: invalid args_mode \"prepend\", expected \"replace\" or \"append\"",
+		}},
+		{"overlays", "source_path: " + mainFile + "\ncluster: dev\nnamespace: shop\ndeployment: search\noverlays:\n  - type: json\n    pach: x\n", patch, []string{
+			"f.yaml:7:5: unknown key pach, did you mean patch?",
+		}},
+		{"project", "defaults:\n  cluster: dev\n  namespace: shop\ntargets:\n  search:\n    source_path: " + mainFile + "\n    deployment: search\n  checkout:\n    kind: attach\n    deployment: checkout\n    delve_continue: true\n", (*validator).project, []string{
+			"f.yaml:9:5: missing required key attach_to",
+			"f.yaml:11:5: unknown key delve_continue",
+		}},
+		{"project kind", "targets:\n  search:\n    kind: debug\n", (*validator).project, []string{
+			"f.yaml:3:11: target \"search\" has invalid kind \"debug\", expected \"patch\" or \"attach\"",
+		}},
+		{"syntax", "cluster: dev\n  namespace: shop\n", patch, []string{
+			"f.yaml:2:1: mapping values are not allowed in this context",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// relative source paths are resolved against the directory of the config
+			v := &validator{path: filepath.Join(dir, "config", "f.yaml"), required: true, contexts: func() ([]string, error) {
+				return []string{"dev"}, nil
+			}}
+			err := v.bytes([]byte(tt.yaml), tt.check)
+			var got []string
+			var validationErr *ValidationError
+			if errors.As(err, &validationErr) {
+				for _, p := range validationErr.Problems {
+					p.File = filepath.Base(p.File)
+					got = append(got, p.String())
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("problems = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package delve
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+)
+
+const (
+	ProtocolRPC = "rpc"
+	ProtocolDAP = "dap"
+)
+
+func ValidateProtocol(protocol string) error {
+	switch protocol {
+	case ProtocolRPC, ProtocolDAP:
+		return nil
+	}
+	return fmt.Errorf("invalid delve protocol %q, expected %q or %q", protocol, ProtocolRPC, ProtocolDAP)
+}
+
+type dapMessage struct {
+	Seq     int             `json:"seq"`
+	Type    string          `json:"type"`
+	Command string          `json:"command,omitempty"`
+	Event   string          `json:"event,omitempty"`
+	Success bool            `json:"success,omitempty"`
+	Message string          `json:"message,omitempty"`
+	Args    json.RawMessage `json:"arguments,omitempty"`
+}
+
+type KubeDAPClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	seq    int
+}
+
+func NewKubeDAPClient(ctx context.Context, host string, port int) (*KubeDAPClient, error) {
+	d := net.Dialer{}
+	conn, err := d.DialContext(ctx, "tcp", fmt.Sprintf("%v:%v", host, port))
+	if err != nil {
+		return nil, err
+	}
+	return &KubeDAPClient{conn: conn, reader: bufio.NewReader(conn)}, nil
+}
+
+// Initialize runs the dap initialize handshake and validates the response
+func (kdc *KubeDAPClient) Initialize() error {
+	args, err := json.Marshal(map[string]interface{}{
+		"clientID":        "gograpple",
+		"adapterID":       "go",
+		"pathFormat":      "path",
+		"linesStartAt1":   true,
+		"columnsStartAt1": true,
+	})
+	if err != nil {
+		return err
+	}
+	if err := kdc.send(dapMessage{Type: "request", Command: "initialize", Args: args}); err != nil {
+		return err
+	}
+	for {
+		msg, err := kdc.read()
+		if err != nil {
+			return err
+		}
+		// skip events the server might send before the response
+		if msg.Type != "response" || msg.Command != "initialize" {
+			continue
+		}
+		if !msg.Success {
+			return fmt.Errorf("dap initialize failed: %v", msg.Message)
+		}
+		return nil
+	}
+}
+
+func (kdc *KubeDAPClient) send(msg dapMessage) error {
+	kdc.seq++
+	msg.Seq = kdc.seq
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(kdc.conn, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+func (kdc *KubeDAPClient) read() (*dapMessage, error) {
+	header, err := textproto.NewReader(kdc.reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid dap header: %v", err)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(kdc.reader, body); err != nil {
+		return nil, err
+	}
+	var msg dapMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (kdc KubeDAPClient) Close() error {
+	return kdc.conn.Close()
+}
@@ -0,0 +1,61 @@
+package delve
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strconv"
+	"testing"
+)
+
+func fakeDAPServer(t *testing.T, response string) *net.TCPAddr {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		header, err := textproto.NewReader(r).ReadMIMEHeader()
+		if err != nil {
+			return
+		}
+		length, _ := strconv.Atoi(header.Get("Content-Length"))
+		_, _ = r.Discard(length)
+		event := `{"seq":1,"type":"event","event":"output"}`
+		fmt.Fprintf(conn, "Content-Length: %d\r\n\r\n%s", len(event), event)
+		fmt.Fprintf(conn, "Content-Length: %d\r\n\r\n%s", len(response), response)
+	}()
+	return l.Addr().(*net.TCPAddr)
+}
+
+func TestKubeDAPClient_Initialize(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		wantErr  bool
+	}{
+		{"success", `{"seq":2,"type":"response","command":"initialize","success":true}`, false},
+		{"failure", `{"seq":2,"type":"response","command":"initialize","success":false,"message":"nope"}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := fakeDAPServer(t, tt.response)
+			dc, err := NewKubeDAPClient(context.Background(), addr.IP.String(), addr.Port)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer dc.Close()
+			if err := dc.Initialize(); (err != nil) != tt.wantErr {
+				t.Errorf("KubeDAPClient.Initialize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/foomo/gograpple/internal/exec"
 	"github.com/pkg/errors"
@@ -11,10 +12,15 @@ import (
 )
 
 type KubeDelveServer struct {
-	host    string
-	port    int
-	kubeCmd *exec.KubectlCmd
-	process *os.Process
+	host       string
+	port       int
+	workingDir string
+	outputDir  string
+	env        []string
+	wrapper    []string
+	protocol   string
+	kubeCmd    *exec.KubectlCmd
+	process    *os.Process
 }
 
 func (kds KubeDelveServer) Host() string {
@@ -28,7 +34,41 @@ func (kds KubeDelveServer) Port() int {
 func NewKubeDelveServer(l *logrus.Entry, namespace, host string, port int) *KubeDelveServer {
 	kubectl := exec.NewKubectlCommand()
 	kubectl.Logger(l).Quiet().Args("-n", namespace)
-	return &KubeDelveServer{host, port, kubectl, nil}
+	return &KubeDelveServer{host: host, port: port, protocol: ProtocolRPC, kubeCmd: kubectl}
+}
+
+// Protocol sets the protocol the delve server will speak, either ProtocolRPC or ProtocolDAP
+func (kds *KubeDelveServer) Protocol(protocol string) *KubeDelveServer {
+	kds.protocol = protocol
+	return kds
+}
+
+// WorkingDir sets the working directory of the debugged program
+func (kds *KubeDelveServer) WorkingDir(dir string) *KubeDelveServer {
+	kds.workingDir = dir
+	return kds
+}
+
+// Output redirects the program stdout and stderr into files in dir instead of the container log
+func (kds *KubeDelveServer) Output(dir string) *KubeDelveServer {
+	kds.outputDir = dir
+	return kds
+}
+
+// Env adds KEY=value pairs to the environment of dlv and the debugged program
+func (kds *KubeDelveServer) Env(env ...string) *KubeDelveServer {
+	kds.env = append(kds.env, env...)
+	return kds
+}
+
+// Wrapper runs dlv through the command that ran the original binary, e.g. an entrypoint script
+func (kds *KubeDelveServer) Wrapper(cmd ...string) *KubeDelveServer {
+	kds.wrapper = append(kds.wrapper, cmd...)
+	return kds
+}
+
+func (kds KubeDelveServer) environ() []string {
+	return append([]string{}, kds.env...)
 }
 
 func (kds *KubeDelveServer) StartNoWait(ctx context.Context, pod, container string,
@@ -56,10 +96,25 @@ func (kds *KubeDelveServer) Start(ctx context.Context, pod, container string,
 
 // doContinue will start the execution without waiting for a client connection
 func (kds KubeDelveServer) getRunCmd(binDest string, binArgs []string, doContinue bool) []string {
-	cmd := []string{
+	if kds.protocol == ProtocolDAP {
+		return kds.getDAPRunCmd()
+	}
+	var cmd []string
+	if env := kds.environ(); len(env) > 0 {
+		cmd = append(append(cmd, "env"), env...)
+	}
+	cmd = append(cmd, kds.wrapper...)
+	stdout, stderr := "/proc/1/fd/1", "/proc/1/fd/1"
+	if kds.outputDir != "" {
+		stdout, stderr = kds.outputDir+"/stdout", kds.outputDir+"/stderr"
+	}
+	cmd = append(cmd,
 		"dlv", "exec", binDest, "--headless", "--api-version=2", "--accept-multiclient",
-		"-r", "stdout:/proc/1/fd/1", "-r", "stderr:/proc/1/fd/1",
+		"-r", "stdout:"+stdout, "-r", "stderr:"+stderr,
 		fmt.Sprintf("--listen=:%v", kds.port),
+	)
+	if kds.workingDir != "" {
+		cmd = append(cmd, "--wd", kds.workingDir)
 	}
 	if doContinue {
 		cmd = append(cmd, "--continue")
@@ -72,6 +127,28 @@ func (kds KubeDelveServer) getRunCmd(binDest string, binArgs []string, doContinu
 	return cmd
 }
 
+// DAPLoopMarker is part of the dap server loop command line so it can be found and killed
+const DAPLoopMarker = "gograpple-dap-loop"
+
+// getDAPRunCmd runs dlv dap in a loop, since the dap server exits once its client disconnects,
+// the debugged binary is started by the client with a launch request and inherits the dlv env
+func (kds KubeDelveServer) getDAPRunCmd() []string {
+	dlv := fmt.Sprintf("dlv dap --listen=:%v", kds.port)
+	if env := kds.environ(); len(env) > 0 {
+		quoted := make([]string, len(env))
+		for i, e := range env {
+			quoted[i] = shellQuote(e)
+		}
+		dlv = fmt.Sprintf("env %v %v", strings.Join(quoted, " "), dlv)
+	}
+	return []string{"sh", "-c", fmt.Sprintf(": %v; while true; do %v; sleep 1; done", DAPLoopMarker, dlv)}
+}
+
+// shellQuote single quotes s for sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
 func (kds *KubeDelveServer) Stop() error {
 	if kds.process == nil {
 		return fmt.Errorf("no process found, run Start first")
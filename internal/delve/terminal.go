@@ -0,0 +1,361 @@
+package delve
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/c-bata/go-prompt"
+	"github.com/foomo/gograpple/internal/lifecycle"
+	"github.com/foomo/gograpple/internal/suggest"
+	"github.com/go-delve/delve/service/api"
+)
+
+var defaultLoadConfig = api.LoadConfig{
+	FollowPointers:     true,
+	MaxVariableRecurse: 1,
+	MaxStringLen:       64,
+	MaxArrayValues:     64,
+	MaxStructFields:    -1,
+}
+
+type terminalCommand struct {
+	aliases []string
+	help    string
+	fn      func(t *Terminal, args string) error
+}
+
+var terminalCommands = []terminalCommand{
+	{[]string{"break", "b"}, "set a breakpoint at <file:line> or <function>", (*Terminal).breakpoint},
+	{[]string{"clear"}, "delete breakpoint <id>", (*Terminal).clear},
+	{[]string{"breakpoints", "bp"}, "list breakpoints", (*Terminal).breakpoints},
+	{[]string{"continue", "c"}, "run until breakpoint or program termination, ctrl+c halts", (*Terminal).cont},
+	{[]string{"next", "n"}, "step over to next source line", (*Terminal).next},
+	{[]string{"step", "s"}, "single step through program", (*Terminal).step},
+	{[]string{"stepout", "so"}, "step out of the current function", (*Terminal).stepOut},
+	{[]string{"goroutines", "grs"}, "list program goroutines", (*Terminal).goroutines},
+	{[]string{"goroutine", "gr"}, "switch to goroutine <id>", (*Terminal).goroutine},
+	{[]string{"stack", "bt"}, "print stack trace of the selected goroutine", (*Terminal).stack},
+	{[]string{"locals"}, "print local variables and function arguments", (*Terminal).locals},
+	{[]string{"print", "p"}, "evaluate an expression", (*Terminal).print},
+	{[]string{"help", "h"}, "print this help", nil},
+	{[]string{"exit", "quit", "q"}, "disconnect and leave the program running", nil},
+}
+
+func init() {
+	// help lists terminalCommands, so it can only be set after their initialization
+	for i := range terminalCommands {
+		if terminalCommands[i].aliases[0] == "help" {
+			terminalCommands[i].fn = (*Terminal).help
+		}
+	}
+}
+
+// Terminal is a minimal interactive debugger client running on a KubeDelveClient
+type Terminal struct {
+	client          *KubeDelveClient
+	moduleRoot      string
+	substitutePaths [][2]string
+	files           []string
+	out             io.Writer
+}
+
+func NewTerminal(client *KubeDelveClient, moduleRoot string) *Terminal {
+	return &Terminal{client: client, moduleRoot: moduleRoot, out: os.Stdout}
+}
+
+// SubstitutePath maps the local directory from onto the directory to recorded in the binary when resolving locations
+func (t *Terminal) SubstitutePath(from, to string) *Terminal {
+	t.substitutePaths = append(t.substitutePaths, [2]string{from, to})
+	return t
+}
+
+func (t *Terminal) Run() error {
+	files, err := sourceFiles(t.moduleRoot)
+	if err != nil {
+		return err
+	}
+	t.files = files
+	fmt.Fprintln(t.out, "type 'help' for a list of commands")
+	for {
+		line := strings.TrimSpace(prompt.Input("(dlv) ", t.complete,
+			prompt.OptionPrefixTextColor(prompt.Fuchsia),
+			prompt.OptionCompletionWordSeparator(" ")))
+		if line == "" {
+			continue
+		}
+		exit, err := t.Execute(line)
+		if err != nil {
+			fmt.Fprintf(t.out, "error: %v\n", err)
+		}
+		if exit {
+			return t.client.Disconnect(true)
+		}
+	}
+}
+
+// Execute runs a single command line, exit is true once the user wants to leave
+func (t *Terminal) Execute(line string) (exit bool, err error) {
+	name, args, _ := strings.Cut(line, " ")
+	for _, cmd := range terminalCommands {
+		for _, alias := range cmd.aliases {
+			if alias != name {
+				continue
+			}
+			if cmd.fn == nil {
+				return true, nil
+			}
+			return false, cmd.fn(t, strings.TrimSpace(args))
+		}
+	}
+	return false, fmt.Errorf("unknown command %q", name)
+}
+
+func (t *Terminal) complete(d prompt.Document) []prompt.Suggest {
+	before := d.TextBeforeCursor()
+	if !strings.Contains(before, " ") {
+		var names []string
+		for _, cmd := range terminalCommands {
+			names = append(names, cmd.aliases[0])
+		}
+		return suggest.Completer(d, names)
+	}
+	switch strings.Fields(before)[0] {
+	case "break", "b":
+		return suggest.Completer(d, t.files)
+	}
+	return nil
+}
+
+func (t *Terminal) breakpoint(args string) error {
+	if args == "" {
+		return fmt.Errorf("missing location, use <file:line> or <function>")
+	}
+	locs, err := t.client.FindLocation(api.EvalScope{GoroutineID: -1}, args, false, t.substitutePaths)
+	if err != nil {
+		return err
+	}
+	if len(locs) == 0 {
+		return fmt.Errorf("location %q not found", args)
+	}
+	bp, err := t.client.CreateBreakpoint(&api.Breakpoint{File: locs[0].File, Line: locs[0].Line})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(t.out, "breakpoint %d set at %v\n", bp.ID, formatLocation(bp.FunctionName, bp.File, bp.Line))
+	return nil
+}
+
+func (t *Terminal) clear(args string) error {
+	id, err := strconv.Atoi(args)
+	if err != nil {
+		return fmt.Errorf("invalid breakpoint id %q", args)
+	}
+	bp, err := t.client.ClearBreakpoint(id)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(t.out, "breakpoint %d cleared at %v\n", bp.ID, formatLocation(bp.FunctionName, bp.File, bp.Line))
+	return nil
+}
+
+func (t *Terminal) breakpoints(_ string) error {
+	bps, err := t.client.ListBreakpoints(false)
+	if err != nil {
+		return err
+	}
+	for _, bp := range bps {
+		if bp.ID < 0 {
+			// internal breakpoints like unrecovered-panic
+			continue
+		}
+		fmt.Fprintf(t.out, "breakpoint %d at %v (%d hits)\n", bp.ID, formatLocation(bp.FunctionName, bp.File, bp.Line), bp.TotalHitCount)
+	}
+	return nil
+}
+
+func (t *Terminal) cont(_ string) error {
+	// halt the program instead of exiting on ctrl+c
+	signalChan, release := lifecycle.Claim()
+	defer release()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-signalChan:
+			_, _ = t.client.Halt()
+		case <-done:
+		}
+	}()
+	for state := range t.client.Continue() {
+		if err := t.printState(state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *Terminal) next(_ string) error {
+	state, err := t.client.Next()
+	if err != nil {
+		return err
+	}
+	return t.printState(state)
+}
+
+func (t *Terminal) step(_ string) error {
+	state, err := t.client.Step()
+	if err != nil {
+		return err
+	}
+	return t.printState(state)
+}
+
+func (t *Terminal) stepOut(_ string) error {
+	state, err := t.client.StepOut()
+	if err != nil {
+		return err
+	}
+	return t.printState(state)
+}
+
+func (t *Terminal) goroutines(_ string) error {
+	state, err := t.client.GetState()
+	if err != nil {
+		return err
+	}
+	grs, _, err := t.client.ListGoroutines(0, 0)
+	if err != nil {
+		return err
+	}
+	for _, gr := range grs {
+		marker := " "
+		if state.SelectedGoroutine != nil && state.SelectedGoroutine.ID == gr.ID {
+			marker = "*"
+		}
+		loc := gr.UserCurrentLoc
+		fmt.Fprintf(t.out, "%v goroutine %d - %v\n", marker, gr.ID, formatLocation(functionName(loc.Function), loc.File, loc.Line))
+	}
+	fmt.Fprintf(t.out, "[%d goroutines]\n", len(grs))
+	return nil
+}
+
+func (t *Terminal) goroutine(args string) error {
+	id, err := strconv.Atoi(args)
+	if err != nil {
+		return fmt.Errorf("invalid goroutine id %q", args)
+	}
+	state, err := t.client.SwitchGoroutine(id)
+	if err != nil {
+		return err
+	}
+	return t.printState(state)
+}
+
+func (t *Terminal) stack(_ string) error {
+	frames, err := t.client.Stacktrace(-1, 50, 0, nil)
+	if err != nil {
+		return err
+	}
+	for i, frame := range frames {
+		fmt.Fprintf(t.out, "%2d  %v\n", i, formatLocation(functionName(frame.Function), frame.File, frame.Line))
+	}
+	return nil
+}
+
+func (t *Terminal) locals(_ string) error {
+	scope := api.EvalScope{GoroutineID: -1}
+	args, err := t.client.ListFunctionArgs(scope, defaultLoadConfig)
+	if err != nil {
+		return err
+	}
+	locals, err := t.client.ListLocalVariables(scope, defaultLoadConfig)
+	if err != nil {
+		return err
+	}
+	for _, v := range append(args, locals...) {
+		fmt.Fprintf(t.out, "%v = %v\n", v.Name, v.SinglelineString())
+	}
+	return nil
+}
+
+func (t *Terminal) print(args string) error {
+	v, err := t.client.EvalVariable(api.EvalScope{GoroutineID: -1}, args, defaultLoadConfig)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(t.out, v.MultilineString("", ""))
+	return nil
+}
+
+func (t *Terminal) help(_ string) error {
+	for _, cmd := range terminalCommands {
+		fmt.Fprintf(t.out, "%-24v %v\n", strings.Join(cmd.aliases, ", "), cmd.help)
+	}
+	return nil
+}
+
+func (t *Terminal) printState(state *api.DebuggerState) error {
+	if state.Err != nil {
+		return state.Err
+	}
+	if state.Exited {
+		fmt.Fprintf(t.out, "process exited with status %d\n", state.ExitStatus)
+		return nil
+	}
+	if state.Running || state.CurrentThread == nil {
+		return nil
+	}
+	th := state.CurrentThread
+	if th.Breakpoint != nil && th.Breakpoint.ID > 0 {
+		fmt.Fprintf(t.out, "> breakpoint %d ", th.Breakpoint.ID)
+	} else {
+		fmt.Fprint(t.out, "> ")
+	}
+	goroutineID := th.GoroutineID
+	if state.SelectedGoroutine != nil {
+		goroutineID = state.SelectedGoroutine.ID
+	}
+	fmt.Fprintf(t.out, "goroutine %d %v\n", goroutineID, formatLocation(functionName(th.Function), th.File, th.Line))
+	return nil
+}
+
+func functionName(f *api.Function) string {
+	if f == nil {
+		return ""
+	}
+	return f.Name()
+}
+
+func formatLocation(function, file string, line int) string {
+	if function == "" {
+		return fmt.Sprintf("%v:%d", file, line)
+	}
+	return fmt.Sprintf("%v() %v:%d", function, file, line)
+}
+
+// sourceFiles lists the go files below root relative to it, for breakpoint completion
+func sourceFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && p != root && (strings.HasPrefix(d.Name(), ".") || d.Name() == "vendor") {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".go") && !strings.HasSuffix(d.Name(), "_test.go") {
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel)+":")
+		}
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,29 @@
+package delve
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_sourceFiles(t *testing.T) {
+	root := t.TempDir()
+	for _, f := range []string{"main.go", "main_test.go", "pkg/handler.go", "vendor/dep/dep.go", ".git/x.go", "README.md"} {
+		p := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := sourceFiles(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"main.go:", "pkg/handler.go:"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sourceFiles() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,124 @@
+package delve
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-delve/delve/service/api"
+)
+
+// TraceHit is a single tracepoint hit, written as a json line
+type TraceHit struct {
+	Time      time.Time         `json:"time"`
+	Goroutine int               `json:"goroutine"`
+	Function  string            `json:"function,omitempty"`
+	Location  string            `json:"location"`
+	Values    map[string]string `json:"values,omitempty"`
+}
+
+// Tracer sets non stopping breakpoints and streams their hits
+type Tracer struct {
+	client          *KubeDelveClient
+	out             io.Writer
+	substitutePaths [][2]string
+	tracepoints     []*api.Breakpoint
+}
+
+func NewTracer(client *KubeDelveClient, out io.Writer) *Tracer {
+	return &Tracer{client: client, out: out}
+}
+
+// SubstitutePath maps the local directory from onto the directory to recorded in the binary when resolving locations
+func (t *Tracer) SubstitutePath(from, to string) *Tracer {
+	t.substitutePaths = append(t.substitutePaths, [2]string{from, to})
+	return t
+}
+
+// SetTracepoints creates a tracepoint evaluating exprs for every location
+func (t *Tracer) SetTracepoints(locations, exprs []string) error {
+	for _, loc := range locations {
+		locs, err := t.client.FindLocation(api.EvalScope{GoroutineID: -1}, loc, false, t.substitutePaths)
+		if err != nil {
+			return err
+		}
+		if len(locs) == 0 {
+			return fmt.Errorf("location %q not found", loc)
+		}
+		bp, err := t.client.CreateBreakpoint(&api.Breakpoint{
+			File:       locs[0].File,
+			Line:       locs[0].Line,
+			Tracepoint: true,
+			Variables:  exprs,
+		})
+		if err != nil {
+			return err
+		}
+		t.tracepoints = append(t.tracepoints, bp)
+	}
+	return nil
+}
+
+// Stream continues the program and writes every tracepoint hit until ctx is done
+func (t *Tracer) Stream(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		// stops the continue loop
+		_, _ = t.client.Halt()
+	}()
+	enc := json.NewEncoder(t.out)
+	for state := range t.client.Continue() {
+		if state.Err != nil {
+			return state.Err
+		}
+		if state.Exited {
+			return fmt.Errorf("process exited with status %d", state.ExitStatus)
+		}
+		for _, th := range state.Threads {
+			if th.Breakpoint == nil || !th.Breakpoint.Tracepoint {
+				continue
+			}
+			if err := enc.Encode(newTraceHit(th)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Clear removes all tracepoints set by SetTracepoints
+func (t *Tracer) Clear() error {
+	var errs []error
+	for _, bp := range t.tracepoints {
+		if _, err := t.client.ClearBreakpoint(bp.ID); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	t.tracepoints = nil
+	if len(errs) > 0 {
+		return fmt.Errorf("couldnt clear %d tracepoints: %v", len(errs), errs)
+	}
+	return nil
+}
+
+func newTraceHit(th *api.Thread) TraceHit {
+	hit := TraceHit{
+		Time:      time.Now(),
+		Goroutine: th.GoroutineID,
+		Function:  functionName(th.Function),
+		Location:  fmt.Sprintf("%v:%d", th.File, th.Line),
+	}
+	if th.BreakpointInfo != nil && len(th.BreakpointInfo.Variables) > 0 {
+		hit.Values = map[string]string{}
+		for _, v := range th.BreakpointInfo.Variables {
+			if v.Unreadable != "" {
+				hit.Values[v.Name] = fmt.Sprintf("error: %v", v.Unreadable)
+				continue
+			}
+			hit.Values[v.Name] = v.SinglelineString()
+		}
+	}
+	return hit
+}
@@ -3,10 +3,13 @@ package exec
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"os"
 	goexec "os/exec"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -26,6 +29,7 @@ type Cmd struct {
 	chanStarted   chan struct{}
 	chanDone      chan struct{}
 	quiet         bool
+	recorder      *Recorder
 }
 
 func NewCommand(name string) *Cmd {
@@ -33,8 +37,8 @@ func NewCommand(name string) *Cmd {
 		command:     []string{name},
 		wait:        true,
 		env:         os.Environ(),
-		chanStarted: make(chan struct{}),
-		chanDone:    make(chan struct{}),
+		chanStarted: make(chan struct{}, 1),
+		chanDone:    make(chan struct{}, 1),
 	}
 }
 
@@ -117,7 +121,27 @@ func (c *Cmd) Quiet() *Cmd {
 	return c
 }
 
+// DryRun records mutating commands into r instead of running them, read-only commands still run
+func (c *Cmd) DryRun(r *Recorder) *Cmd {
+	c.recorder = r
+	return c
+}
+
 func (c *Cmd) Run(ctx context.Context) (string, error) {
+	if c.recorder != nil && isMutating(c.command) {
+		c.recorder.record(c.command)
+		if c.l != nil {
+			c.l.Debugf("dry-run: skipping %q", strings.Join(c.command, " "))
+		}
+		// nobody may be waiting, a full channel already signals it
+		for _, ch := range []chan struct{}{c.chanStarted, c.chanDone} {
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+		return "", nil
+	}
 	return c.run(goexec.CommandContext(ctx, c.command[0], c.command[1:]...))
 }
 
@@ -156,10 +180,15 @@ func (c *Cmd) run(cmd *goexec.Cmd) (string, error) {
 		}
 	}
 
+	start := time.Now()
 	if err := cmd.Start(); err != nil {
+		c.logEvent(start, err)
 		return "", err
 	}
 
+	if !c.wait {
+		c.logEvent(start, nil)
+	}
 	if c.postStartFunc != nil {
 		if err := c.postStartFunc(cmd.Process); err != nil {
 			return "", err
@@ -171,7 +200,9 @@ func (c *Cmd) run(cmd *goexec.Cmd) (string, error) {
 	}()
 
 	if c.wait {
-		if err := cmd.Wait(); err != nil {
+		err := cmd.Wait()
+		c.logEvent(start, err)
+		if err != nil {
 			return "", err
 		}
 		if c.postEndFunc != nil {
@@ -187,3 +218,36 @@ func (c *Cmd) run(cmd *goexec.Cmd) (string, error) {
 
 	return combinedBuf.String(), nil
 }
+
+// logEvent logs the executed command with its duration and exit code as structured fields,
+// commands that dont wait are logged once started with an exit code of -1
+func (c *Cmd) logEvent(start time.Time, err error) {
+	if c.l == nil {
+		return
+	}
+	exitCode := 0
+	if !c.wait {
+		exitCode = -1
+	}
+	var exitErr *goexec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		exitCode = -1
+	}
+	entry := c.l.WithFields(logrus.Fields{
+		"event":       "exec",
+		"cmd":         c.command[0],
+		"args":        c.command[1:],
+		"duration_ms": time.Since(start).Milliseconds(),
+		"exit_code":   exitCode,
+	})
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+	if !c.wait {
+		entry.Debugf("started %v", c.command[0])
+		return
+	}
+	entry.Debugf("executed %v", c.command[0])
+}
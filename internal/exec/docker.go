@@ -2,6 +2,7 @@ package exec
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"strings"
 )
@@ -38,6 +39,27 @@ func (c DockerCmd) GetPlatform(ctx context.Context, image string) (*Platform, er
 	return NewPlatform(strings.TrimRight(out, "\n"))
 }
 
+// ImageConfig holds the runtime defaults of an image
+type ImageConfig struct {
+	User       string   `json:"User"`
+	Env        []string `json:"Env"`
+	Entrypoint []string `json:"Entrypoint"`
+	Cmd        []string `json:"Cmd"`
+	WorkingDir string   `json:"WorkingDir"`
+}
+
+func (c DockerCmd) GetImageConfig(ctx context.Context, image string) (*ImageConfig, error) {
+	out, err := c.ImageInspect("-f", "{{json .Config}}", image).Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var ic ImageConfig
+	if err := json.Unmarshal([]byte(out), &ic); err != nil {
+		return nil, err
+	}
+	return &ic, nil
+}
+
 type Platform struct {
 	OS   string
 	Arch string
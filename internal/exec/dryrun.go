@@ -0,0 +1,115 @@
+package exec
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Recorder collects mutating commands and planned artifacts instead of executing them
+type Recorder struct {
+	mu        sync.Mutex
+	commands  [][]string
+	artifacts []artifact
+}
+
+type artifact struct {
+	title   string
+	content string
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+func (r *Recorder) record(command []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commands = append(r.commands, append([]string{}, command...))
+}
+
+// Add records a planned artifact like a rendered patch
+func (r *Recorder) Add(title, content string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.artifacts = append(r.artifacts, artifact{title, content})
+}
+
+func (r *Recorder) Commands() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.commands
+}
+
+// Print writes the artifacts followed by the recorded commands
+func (r *Recorder) Print(w io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, a := range r.artifacts {
+		fmt.Fprintf(w, "--- %v\n%v\n", a.title, strings.TrimRight(a.content, "\n"))
+	}
+	fmt.Fprintln(w, "--- commands")
+	for _, command := range r.commands {
+		fmt.Fprintln(w, strings.Join(command, " "))
+	}
+}
+
+var (
+	mutatingKubectlVerbs = []string{"patch", "create", "delete", "cp", "exec", "apply", "annotate", "label", "expose", "scale", "replace", "set", "edit"}
+	mutatingDockerVerbs  = []string{"build", "push", "tag", "rm", "rmi", "run"}
+	mutatingGoVerbs      = []string{"build", "install"}
+)
+
+// kubectlValueFlags are the flags in front of the kubectl verb that take a separate value
+var kubectlValueFlags = []string{"-n", "--namespace", "--context", "--cluster", "--user", "-s", "--server", "--kubeconfig",
+	"--token", "--as", "--as-group", "--request-timeout", "-l", "--selector", "--cache-dir", "-v", "--v"}
+
+// isMutating tells whether command changes the cluster, images or local build outputs
+func isMutating(command []string) bool {
+	if len(command) < 2 {
+		return false
+	}
+	args := command[1:]
+	for _, arg := range args {
+		if arg == "--" {
+			// the rest belongs to the command run in the pod
+			break
+		}
+		if arg == "--dry-run=server" || arg == "--dry-run=client" || arg == "--local" {
+			return false
+		}
+	}
+	switch command[0] {
+	case "kubectl":
+		verb, rest := positional(args)
+		switch {
+		case stringIsInSlice(verb, mutatingKubectlVerbs):
+			return true
+		case verb == "rollout":
+			sub, _ := positional(rest)
+			return sub != "" && sub != "status" && sub != "history"
+		}
+	case "docker":
+		return stringIsInSlice(args[0], mutatingDockerVerbs)
+	case "go":
+		return stringIsInSlice(args[0], mutatingGoVerbs)
+	}
+	return false
+}
+
+// positional returns the first argument that is neither a flag nor the value of one and the arguments after it
+func positional(args []string) (string, []string) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return "", nil
+		case !strings.HasPrefix(arg, "-"):
+			return arg, args[i+1:]
+		case !strings.Contains(arg, "=") && stringIsInSlice(arg, kubectlValueFlags):
+			i++
+		}
+	}
+	return "", nil
+}
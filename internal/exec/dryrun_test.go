@@ -0,0 +1,42 @@
+package exec
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_isMutating(t *testing.T) {
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{"kubectl -n dev get deployment app -o json", false},
+		{"kubectl -n dev --selector app=x get pods", false},
+		{"kubectl -n dev patch deployment app --patch {}", true},
+		{"kubectl -n dev create configmap app-patch", true},
+		{"kubectl -n dev patch deployment app --patch {} --dry-run=server -o json", false},
+		{"kubectl -n dev patch --local -f /tmp/app.json --type json --patch [] -o json", false},
+		{"kubectl -n dev exec app-123 -c app -- pidof app", true},
+		{"kubectl -n dev rollout status deployment app -w", false},
+		{"kubectl -n dev rollout undo deployment app", true},
+		{"kubectl -n config patch deployment app --patch {}", true},
+		{"kubectl --namespace=patch get deployment set -o json", false},
+		{"kubectl -n set --context patch describe pod app", false},
+		{"kubectl --kubeconfig /tmp/get --insecure-skip-tls-verify delete pod app", true},
+		{"kubectl -n dev rollout -w status deployment app", false},
+		{"kubectl -n dev exec app-123 -- kubectl --local get", true},
+		{"docker pull alpine:latest", false},
+		{"docker image inspect -f {{.Os}} alpine", false},
+		{"docker build /tmp/the-hook -t app-patch:latest", true},
+		{"docker push app-patch:latest", true},
+		{"go build -o /tmp/app main.go", true},
+		{"go env GOPATH", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			if got := isMutating(strings.Fields(tt.command)); got != tt.want {
+				t.Errorf("isMutating() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
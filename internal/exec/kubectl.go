@@ -1,10 +1,12 @@
 package exec
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -72,18 +74,51 @@ func (c KubectlCmd) ExecShell(resource, path string) *Cmd {
 	).Stdin(os.Stdin).Stdout(os.Stdout).Stderr(os.Stdout)
 }
 
-func (c KubectlCmd) PatchDeployment(patch, deployment string) *Cmd {
-	return c.Args("patch", "deployment", deployment, "--patch", patch)
+// PatchDeployment patches the deployment, patchType is one of strategic, merge or json
+func (c KubectlCmd) PatchDeployment(patch, patchType, deployment string) *Cmd {
+	return c.Args("patch", "deployment", deployment, "--type", patchType, "--patch", patch)
+}
+
+// PatchDeploymentDryRun returns the deployment the api server would store after applying the patch
+func (c KubectlCmd) PatchDeploymentDryRun(ctx context.Context, patch, patchType, deployment string) (*apps.Deployment, error) {
+	out, err := c.PatchDeployment(patch, patchType, deployment).Args("--dry-run=server", "-o", "json").Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var d apps.Deployment
+	if err := json.Unmarshal([]byte(out), &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// PatchFile patches the resource in file locally and returns the result as json, nothing is sent to the cluster
+func (c KubectlCmd) PatchFile(ctx context.Context, file, patch, patchType string) (string, error) {
+	stderr := new(bytes.Buffer)
+	out, err := c.Args("patch", "--local", "-f", file, "--type", patchType, "--patch", patch, "-o", "json").Stderr(stderr).Quiet().Run(ctx)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
 }
 
 func (c KubectlCmd) CopyToPod(pod, container, source, destination string) *Cmd {
 	return c.Args("cp", source, fmt.Sprintf("%v:%v", pod, destination), "-c", container)
 }
 
+func (c KubectlCmd) CopyFromPod(pod, container, source, destination string) *Cmd {
+	return c.Args("cp", fmt.Sprintf("%v:%v", pod, source), destination, "-c", container)
+}
+
 func (c KubectlCmd) ExecPod(pod, container string, cmd []string) *Cmd {
 	return c.Args("exec", pod, "-c", container, "--").Args(cmd...)
 }
 
+// ExecPodInput runs cmd in the container with in attached to its stdin
+func (c KubectlCmd) ExecPodInput(pod, container string, cmd []string, in io.Reader) *Cmd {
+	return c.Args("exec", "-i", pod, "-c", container, "--").Args(cmd...).Stdin(in)
+}
+
 func (c KubectlCmd) ExposePod(pod string, host string, port int) *Cmd {
 	if host == "127.0.0.1" {
 		host = ""
@@ -92,8 +127,26 @@ func (c KubectlCmd) ExposePod(pod string, host string, port int) *Cmd {
 		fmt.Sprintf("--port=%v", port), fmt.Sprintf("--external-ip=%v", host))
 }
 
-func (c KubectlCmd) PortForwardPod(pod string, host string, port int) *Cmd {
-	return c.Args("port-forward", "pods/"+pod, strconv.Itoa(port)+":"+strconv.Itoa(port))
+// PortForwardPod forwards localPort to podPort of the pod
+func (c KubectlCmd) PortForwardPod(pod string, host string, localPort, podPort int) *Cmd {
+	return c.Args("port-forward", "pods/"+pod, strconv.Itoa(localPort)+":"+strconv.Itoa(podPort))
+}
+
+func (c KubectlCmd) AnnotatePod(pod, key, value string) *Cmd {
+	return c.Args("annotate", "pod", pod, fmt.Sprintf("%v=%v", key, value), "--overwrite")
+}
+
+// GetPodAnnotation returns the value of the annotation key of the pod, empty if it is not set
+func (c KubectlCmd) GetPodAnnotation(ctx context.Context, pod, key string) (string, error) {
+	out, err := c.Args("get", "pod", pod, "-o", "json").Quiet().Run(ctx)
+	if err != nil {
+		return "", err
+	}
+	var p core.Pod
+	if err := json.Unmarshal([]byte(out), &p); err != nil {
+		return "", err
+	}
+	return p.Annotations[key], nil
 }
 
 func (c KubectlCmd) DeleteService(service string) *Cmd {
@@ -150,6 +203,14 @@ func (c KubectlCmd) GetContainers(deployment apps.Deployment) []string {
 	return containers
 }
 
+func (c KubectlCmd) GetPodContainers(ctx context.Context, pod string) ([]string, error) {
+	out, err := c.Args("get", "pod", pod, "-o", "jsonpath={.spec.containers[*].name}").Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
+
 func (c KubectlCmd) GetPodsByLabels(ctx context.Context, labels []string) ([]string, error) {
 	out, err := c.Args("get", "pods", "-l", strings.Join(labels, ","), "-o", "name", "-A").Run(ctx)
 	if err != nil {
@@ -192,6 +253,25 @@ func (c KubectlCmd) GetConfigMapKey(ctx context.Context, name, key string) (stri
 	return out, nil
 }
 
+// GetDataKeys lists the data keys of a configmap or secret
+func (c KubectlCmd) GetDataKeys(ctx context.Context, kind, name string) ([]string, error) {
+	out, err := c.Args("get", kind, name, "-o", "jsonpath={.data}").Run(ctx)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]string{}
+	if out != "" {
+		if err := json.Unmarshal([]byte(out), &data); err != nil {
+			return nil, err
+		}
+	}
+	var keys []string
+	for key := range data {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
 func parseResources(out, delimiter, prefix string) ([]string, error) {
 	var res []string
 	if out == "" {
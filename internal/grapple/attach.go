@@ -1,31 +1,44 @@
 package grapple
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"os/signal"
 	"runtime"
+	"strconv"
+	"time"
 
 	"github.com/bitfield/script"
+	"github.com/foomo/gograpple/internal/delve"
 	"github.com/foomo/gograpple/internal/kubectl"
+	"github.com/foomo/gograpple/internal/lifecycle"
 	"github.com/foomo/gograpple/internal/log"
 	"github.com/pkg/errors"
 )
 
-func (g Grapple) Attach(namespace, deployment, container, bin, arch, host string, port int, debug bool) error {
+func (g Grapple) Attach(namespace, deployment, container, bin, arch, host string, port int, sourcePath, buildPath, protocol string, debug bool) error {
+	if err := delve.ValidateProtocol(protocol); err != nil {
+		return err
+	}
 	pod, err := kubectl.GetMostRecentRunningPodBySelectors(namespace, g.deployment.Spec.Selector.MatchLabels)
 	if err != nil {
 		return err
 	}
-	go handleExit(namespace, pod, container)
-	// check if delve is available
-	dlvDest := "dlv"
-	_, err = kubectl.ExecPod(namespace, pod, container, []string{"which", "dlv"}).String()
-	if err != nil {
-		if err := copyDelve(namespace, pod, container, arch, dlvDest); err != nil {
-			return err
+	lifecycle.Register("stop attached delve", time.Minute, func(ctx context.Context) error {
+		return stopDelve(namespace, pod, container)
+	})
+	// the debug client maps the local sources onto the paths the image binary was built from
+	var goModPath string
+	var substitutePaths []substitutePath
+	if sourcePath != "" {
+		if goModPath, err = findGoProjectRoot(sourcePath); err != nil {
+			return fmt.Errorf("couldnt find go.mod path for source %q", sourcePath)
 		}
-		dlvDest = "/dlv"
+		substitutePaths = newSubstitutePaths(goModPath, buildPath)
+	}
+	dlvDest, err := ensureDelve(namespace, pod, container, arch)
+	if err != nil {
+		return err
 	}
 	// find pid of bin by name
 	pids, err := kubectl.GetPIDsOf(namespace, pod, container, bin)
@@ -35,9 +48,30 @@ func (g Grapple) Attach(namespace, deployment, container, bin, arch, host string
 	if len(pids) != 1 {
 		return fmt.Errorf("found none or more than one process named %q", bin)
 	}
-	go attachDelveOnPod(namespace, pod, container, dlvDest, pids[0], host, port, debug)
+	port, podPort, err := g.allocatePorts(host, port)
+	if err != nil {
+		return err
+	}
+	cmd := attachCmd(dlvDest, pids[0], host, podPort, debug)
+	la := newLaunchArgs(launchConfigName(deployment), host, port, substitutePaths)
+	if protocol == delve.ProtocolDAP {
+		g.l.Infof("attach your dap client on %v:%v to process %v", host, port, pids[0])
+		cmd = dapCmd(dlvDest, podPort, debug)
+		pid, err := strconv.Atoi(pids[0])
+		if err != nil {
+			return err
+		}
+		la = newDAPAttachArgs(launchConfigName(deployment), host, port, pid, substitutePaths)
+	}
+	if len(substitutePaths) > 0 {
+		vlog := g.componentLog("vscode")
+		if err := writeVSCodeLaunchConfig(vlog, goModPath, la); err != nil {
+			vlog.WithError(err).Error("couldnt write vscode launch configuration")
+		}
+	}
+	go attachDelveOnPod(namespace, pod, container, cmd)
 	// launchVSCode(context.Background(), g.l, "./test/app", "", port, 3)
-	return kubectl.PortForwardPod(namespace, pod, port)
+	return kubectl.PortForwardPod(namespace, pod, port, podPort)
 }
 
 func attachCmd(dlvPath, binPid, host string, port int, debug bool) []string {
@@ -50,25 +84,32 @@ func attachCmd(dlvPath, binPid, host string, port int, debug bool) []string {
 }
 
 func dapCmd(dlvPath string, port int, debug bool) []string {
-	cmd := []string{dlvPath, "dap", "--listen",
-		fmt.Sprintf("127.0.0.1:%v", port)}
+	cmd := []string{dlvPath, "dap", "--listen", fmt.Sprintf("127.0.0.1:%v", port)}
 	if debug {
 		cmd = append(cmd, "--log", "--log-output=rpc,dap,debugger")
 	}
 	return cmd
 }
 
-func attachDelveOnPod(namespace, pod, container, dlvPath, binPid, host string, port int, debug bool) error {
-	_, err := kubectl.ExecPod(namespace, pod, container, attachCmd(dlvPath, binPid, host, port, debug)).WithStdout(log.Writer("dlv")).Stdout()
+func attachDelveOnPod(namespace, pod, container string, cmd []string) error {
+	_, err := kubectl.ExecPod(namespace, pod, container, cmd).WithStdout(log.Writer("dlv")).Stdout()
+	return err
+}
+
+func stopDelve(namespace, pod, container string) error {
+	_, err := kubectl.ExecPod(namespace, pod, container, []string{"pkill", delveBin}).WithStdout(log.Writer("cleanup")).Stdout()
 	return err
 }
 
-func cleanup(namespace, pod, container string) {
-	pss := []string{"dlv"}
-	for _, ps := range pss {
-		kubectl.ExecPod(namespace, pod, container, []string{"pkill", ps}).WithStdout(log.Writer("cleanup")).Stdout()
-		// script.Exec(fmt.Sprintf("pkill %v", ps)).Stdout()
+// ensureDelve returns the dlv path on the pod, dlv is built and copied if its not available
+func ensureDelve(namespace, pod, container, arch string) (string, error) {
+	if _, err := kubectl.ExecPod(namespace, pod, container, []string{"which", "dlv"}).String(); err == nil {
+		return "dlv", nil
+	}
+	if err := copyDelve(namespace, pod, container, arch, "dlv"); err != nil {
+		return "", err
 	}
+	return "/dlv", nil
 }
 
 func copyDelve(namespace, pod, container, arch, dlvDest string) error {
@@ -89,12 +130,3 @@ func copyDelve(namespace, pod, container, arch, dlvDest string) error {
 	// copy dlv to pod
 	return kubectl.CopyToPod(namespace, pod, container, dlvSrc, dlvDest)
 }
-
-func handleExit(namespace, pod, container string) {
-	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, os.Interrupt)
-	<-signalChan
-	log.Entry("cleanup").Info("exiting")
-	cleanup(namespace, pod, container)
-	os.Exit(0)
-}
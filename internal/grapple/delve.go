@@ -3,58 +3,137 @@ package grapple
 import (
 	"context"
 	"fmt"
-	"os"
 	"path"
+	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/foomo/gograpple/internal/delve"
 	"github.com/foomo/gograpple/internal/exec"
+	"github.com/foomo/gograpple/internal/lifecycle"
 	"github.com/foomo/gograpple/util"
 	"github.com/sirupsen/logrus"
 )
 
 const delveBin = "dlv"
 
-func (g Grapple) Delve(pod, container, sourcePath string, binArgs []string, host string,
-	port int, vscode, delveContinue bool) error {
+// DelveOptions configure the debug run of Delve
+type DelveOptions struct {
+	// Pod and Container default to the most recent pod and the first container of the deployment
+	Pod        string
+	Container  string
+	SourcePath string
+	// Args replace the original args unless AppendArgs is set
+	Args       []string
+	AppendArgs bool
+	// Env is added to the environment of the debug run
+	Env  []string
+	Host string
+	// Port 0 allocates free ports locally and in the pod
+	Port       int
+	IDE        string
+	LaunchJSON bool
+	// RunConfig writes the goland run configuration without opening goland
+	RunConfig    bool
+	Continue     bool
+	TrimPath     bool
+	Protocol     string
+	PprofPort    int
+	StreamOutput bool
+}
+
+// Delve runs the patched container with a debug build of the source path
+func (g Grapple) Delve(o DelveOptions) error {
 	ctx := context.Background()
 	if !g.isPatched() {
 		return fmt.Errorf("deployment not patched, stopping delve")
 	}
+	if err := delve.ValidateProtocol(o.Protocol); err != nil {
+		return err
+	}
+	if err := ValidateIDE(o.IDE); err != nil {
+		return err
+	}
 
+	// reproduce how the original container ran its entrypoint
+	rs, err := g.originalRunSpec(ctx, o.Container)
+	if err != nil {
+		return err
+	}
+	// only the application binary is replaced, a wrapper in front of it keeps running it
+	wrapper, originalArgs, found := rs.split(g.binaryNames(o.SourcePath, o.Container)...)
+	switch {
+	case found:
+		g.l.Infof("replacing the application binary of the original command %q", rs.Command)
+	case len(rs.Command) > 0:
+		g.l.Warnf("couldnt find the application binary in the original command %q, "+
+			"running the debug binary in place of %q with its args", rs.Command, rs.Command[0])
+	}
+	if len(wrapper) > 0 && o.Protocol == delve.ProtocolDAP {
+		g.l.Warnf("the dap client starts the debug binary, it doesnt run through %q", wrapper)
+		wrapper = nil
+	}
 	// populate bin args if empty
-	if len(binArgs) == 0 {
-		var err error
-		d, err := g.kubeCmd.GetDeploymentFromConfigMap(ctx, g.DeploymentConfigMapName(),
-			defaultConfigMapDeploymentKey)
-		if err != nil {
-			return err
-		}
-		c, err := g.kubeCmd.GetContainerFromDeployment(container, d)
-		if err != nil {
-			return err
-		}
-		binArgs = c.Args
+	if len(o.Args) == 0 {
+		o.Args = originalArgs
+	} else if o.AppendArgs {
+		o.Args = append(append([]string{}, originalArgs...), o.Args...)
 	}
 	// validate sourcePath
-	goModPath, err := findGoProjectRoot(sourcePath)
+	goModPath, err := findGoProjectRoot(o.SourcePath)
 	if err != nil {
-		return fmt.Errorf("couldnt find go.mod path for source %q", sourcePath)
+		return fmt.Errorf("couldnt find go.mod path for source %q", o.SourcePath)
+	}
+	// map the local sources onto the paths recorded in the binary
+	substitutePaths, err := debugBuildSubstitutePaths(goModPath, o.TrimPath)
+	if err != nil {
+		return err
 	}
+	// port 0 allocates the ports once, so they stay the same for the ide on reloads
+	port, podPort, err := g.allocatePorts(o.Host, o.Port, o.PprofPort)
+	if err != nil {
+		return err
+	}
+	o.Port = port
 
-	util.RunWithInterrupt(g.l, func(ctx context.Context) {
-		g.l.Infof("waiting for deployment to get ready")
-		_, err := g.kubeCmd.WaitForRollout(g.deployment.Name, defaultWaitTimeout).Run(ctx)
-		if err != nil {
-			g.l.Error(err)
-			return
+	// stopping the session ends the port-forwards and the streamed output
+	session, stopSession := context.WithCancel(context.Background())
+	defer stopSession()
+	lifecycle.Register("remove session state", 5*time.Second, func(ctx context.Context) error {
+		return removeSession(g.cluster, g.deployment.Namespace, g.deployment.Name)
+	})
+	lifecycle.Register("kill delve and application", time.Minute, func(ctx context.Context) error {
+		if o.Pod == "" {
+			return nil
+		}
+		return g.cleanupPIDs(ctx, o.Pod, o.Container)
+	})
+	lifecycle.Register("stop port-forwards", 5*time.Second, func(ctx context.Context) error {
+		stopSession()
+		return nil
+	})
+	util.RunWithInterrupt(session, g.l, func(ctx context.Context) {
+		probes := g.probesStrategy(ctx)
+		if probes == ProbesProxy {
+			g.l.Infof("waiting for patched pod with %v sidecar", probeContainerName)
+			if err := g.waitForProbeSidecar(ctx, 30); err != nil {
+				g.l.Error(err)
+				return
+			}
+		} else {
+			g.l.Infof("waiting for deployment to get ready")
+			_, err := g.kubeCmd.WaitForRollout(g.deployment.Name, defaultWaitTimeout).Run(ctx)
+			if err != nil {
+				g.l.Error(err)
+				return
+			}
 		}
 		// validate and get k8s resources for delve session
-		if err := g.kubeCmd.ValidatePod(context.Background(), g.deployment, &pod); err != nil {
+		if err := g.kubeCmd.ValidatePod(context.Background(), g.deployment, &o.Pod); err != nil {
 			g.l.Error(err)
 			return
 		}
-		if err := g.kubeCmd.ValidateContainer(g.deployment, &container); err != nil {
+		if err := g.kubeCmd.ValidateContainer(g.deployment, &o.Container); err != nil {
 			g.l.Error(err)
 			return
 		}
@@ -62,7 +141,7 @@ func (g Grapple) Delve(pod, container, sourcePath string, binArgs []string, host
 		// run pre-start cleanup
 		clog := g.componentLog("cleanup")
 		clog.Info("running pre-start cleanup")
-		if err := g.cleanupPIDs(ctx, pod, container); err != nil {
+		if err := g.cleanupPIDs(ctx, o.Pod, o.Container); err != nil {
 			clog.Error(err)
 			return
 		}
@@ -71,7 +150,7 @@ func (g Grapple) Delve(pod, container, sourcePath string, binArgs []string, host
 		dlog := g.componentLog("deploy")
 		dlog.Info("building and deploying bin")
 		// get image used in the deployment so we can get platform
-		deploymentImage, err := g.kubeCmd.GetImage(ctx, g.deployment, container)
+		deploymentImage, err := g.kubeCmd.GetImage(ctx, g.deployment, o.Container)
 		if err != nil {
 			dlog.Error(err)
 			return
@@ -82,33 +161,95 @@ func (g Grapple) Delve(pod, container, sourcePath string, binArgs []string, host
 			dlog.Error(err)
 			return
 		}
-		if err := g.deployBin(ctx, pod, container, goModPath, sourcePath, deploymentPlatform); err != nil {
+		binHash, buildHash, err := g.deployBin(ctx, o.Pod, o.Container, goModPath, o.SourcePath, deploymentPlatform, o.TrimPath, o.PprofPort)
+		if err != nil {
 			dlog.Error(err)
 			return
 		}
 		// start delve server
 		dslog := g.componentLog("server")
-		dslog.Infof("starting delve server on %v:%v", host, port)
-		ds := delve.NewKubeDelveServer(dslog, g.deployment.Namespace, host, port)
-		ds.StartNoWait(ctx, pod, container, g.binDestination(), binArgs, delveContinue)
-		dslog.Info("application logs are redirected to your container")
+		dslog.Infof("starting delve server on pod port %v", podPort)
+		ds := delve.NewKubeDelveServer(dslog, g.deployment.Namespace, o.Host, podPort).
+			Protocol(o.Protocol).WorkingDir(rs.WorkingDir).Env(rs.Env...).Env(o.Env...).Wrapper(wrapper...)
+		stream := o.StreamOutput && o.Protocol != delve.ProtocolDAP
+		if o.StreamOutput && !stream {
+			dslog.Warn("streaming the output needs the rpc protocol, it stays in your container log")
+		}
+		if stream {
+			if err := g.prepareOutput(ctx, o.Pod, o.Container); err != nil {
+				dslog.Error(err)
+				return
+			}
+			ds.Output(outputDir)
+		}
+		ds.StartNoWait(ctx, o.Pod, o.Container, g.binDestination(), o.Args, o.Continue)
+		if o.Protocol == delve.ProtocolDAP {
+			dslog.Infof("application %v will be started by your dap client", g.binDestination())
+		} else if stream {
+			dslog.Info("application output is streamed here and to your container log")
+			g.streamOutput(g.componentLog("app"), ctx, o.Pod, o.Container)
+		} else {
+			dslog.Info("application logs are redirected to your container")
+		}
 		// port forward to pod with delve server
 		dclog := g.componentLog("client")
-		g.portForwardDelve(dclog, ctx, pod, host, port)
+		g.portForwardDelve(dclog, ctx, o.Pod, o.Host, o.Port, podPort, o.Protocol)
 		// check server state with delve client
-		if err := g.checkDelveConnection(dclog, ctx, 10, host, port); err != nil {
+		if err := g.checkDelveConnection(dclog, ctx, 10, o.Host, o.Port, o.Protocol); err != nil {
 			dclog.WithError(err).Error("couldnt connect to delver server")
 			return
 		}
-		// launch vscode
-		if vscode {
+		slog := g.componentLog("session")
+		if patchImage, err := g.patchImage(ctx, o.Container); err != nil {
+			slog.WithError(err).Warn("couldnt get the patch image, the session cant be resumed")
+		} else if err := g.saveSession(ctx, Session{
+			Cluster: g.cluster, Namespace: g.deployment.Namespace, Deployment: g.deployment.Name, Container: o.Container,
+			Pod: o.Pod, Host: o.Host, LocalPort: o.Port, PodPort: podPort, Protocol: o.Protocol, BinHash: binHash, BuildHash: buildHash, PatchImage: patchImage,
+		}); err != nil {
+			slog.WithError(err).Warn("couldnt save session state, it cant be resumed")
+		}
+		if probes == ProbesBreakpoint {
+			plog := g.componentLog("probes")
+			if o.Protocol == delve.ProtocolDAP {
+				plog.Warn("readiness on breakpoints needs the rpc protocol, the pod stays ready")
+			} else {
+				go g.watchHalted(plog, ctx, o.Pod, o.Container, o.Host, o.Port)
+			}
+		}
+		// launch ide
+		la := newLaunchArgs(launchConfigName(g.deployment.Name), o.Host, o.Port, substitutePaths)
+		if o.Protocol == delve.ProtocolDAP {
+			la = newDAPLaunchArgs(launchConfigName(g.deployment.Name), o.Host, o.Port, g.binDestination(), o.Args, substitutePaths)
+			la.Cwd = rs.WorkingDir
+		}
+		switch o.IDE {
+		case IDEVSCode:
 			vlog := g.componentLog("vscode")
-			if err := launchVSCode(ctx, vlog, goModPath, host, port, 5); err != nil {
+			if err := launchVSCode(ctx, vlog, goModPath, la, 5, o.LaunchJSON); err != nil {
 				vlog.WithError(err).Error("couldnt launch vscode")
 			}
+		case IDEGoland:
+			glog := g.componentLog("goland")
+			if o.Protocol == delve.ProtocolDAP {
+				glog.Warn("goland does not support dap, use the rpc protocol")
+			}
+			if err := launchGoland(ctx, glog, goModPath, g.deployment.Name, o.Host, o.Port); err != nil {
+				glog.WithError(err).Error("couldnt launch goland")
+			}
+		}
+		if o.LaunchJSON && o.IDE != IDEVSCode {
+			vlog := g.componentLog("vscode")
+			if err := writeVSCodeLaunchConfig(vlog, goModPath, la); err != nil {
+				vlog.WithError(err).Error("couldnt write vscode launch configuration")
+			}
+		}
+		if o.RunConfig && o.IDE != IDEGoland {
+			glog := g.componentLog("goland")
+			if err := writeGolandRunConfig(glog, goModPath, g.deployment.Name, o.Host, o.Port); err != nil {
+				glog.WithError(err).Error("couldnt write goland run configuration")
+			}
 		}
 	})
-	defer g.cleanupPIDs(context.Background(), pod, container)
 	return nil
 }
 func (g Grapple) componentLog(name string) *logrus.Entry {
@@ -119,8 +260,9 @@ func (g Grapple) binName() string {
 	return g.deployment.Name
 }
 
+// binDestination is writable for containers not running as root
 func (g Grapple) binDestination() string {
-	return "/" + g.binName()
+	return path.Join("/tmp", g.binName())
 }
 
 func (g Grapple) cleanupPIDs(ctx context.Context, pod, container string) error {
@@ -133,6 +275,10 @@ func (g Grapple) cleanupPIDs(ctx context.Context, pod, container string) error {
 	if errDelvePids != nil {
 		return errDelvePids
 	}
+	// stop the dap server loop so it wont restart dlv
+	_, _ = g.kubeCmd.ExecPod(pod, container, []string{"pkill", "-f", delve.DAPLoopMarker}).Quiet().Run(ctx)
+	// stop forwarding the output files
+	_, _ = g.kubeCmd.ExecPod(pod, container, []string{"pkill", "-f", outputDir}).Quiet().Run(ctx)
 	// kill pids directly on pod container
 	maxTries := 10
 	pids := append(binPids, delvePids...)
@@ -145,35 +291,76 @@ func (g Grapple) cleanupPIDs(ctx context.Context, pod, container string) error {
 	})
 }
 
-func (g Grapple) deployBin(ctx context.Context, pod, container, goModPath, sourcePath string, p *exec.Platform) error {
+// deployBin builds and copies the bin into the pod, returning the hashes of the bin and of its build inputs,
+// a resumed session skips the build if its inputs are unchanged and the copy if the pod still has the bin
+func (g Grapple) deployBin(ctx context.Context, pod, container, goModPath, sourcePath string, p *exec.Platform, trimPath bool, pprofPort int) (binHash, buildHash string, err error) {
+	dlog := g.componentLog("deploy")
+	absSourcePath, err := filepath.Abs(sourcePath)
+	if err != nil {
+		return "", "", err
+	}
+	buildHash, err = sourcesHash(goModPath, absSourcePath, p.String(), strconv.FormatBool(trimPath), strconv.Itoa(pprofPort))
+	if err != nil {
+		return "", "", err
+	}
+	if g.unchangedBuild(buildHash) && g.unchangedBin(ctx, pod, container, g.resumed.BinHash) {
+		dlog.Info("sources unchanged, skipping build and copy")
+		return g.resumed.BinHash, buildHash, nil
+	}
 	// build bin
-	binSource := path.Join(os.TempDir(), g.binName())
-	_, err := g.goCmd.Build(binSource, []string{sourcePath}, "-gcflags", "-N -l").
+	binSource := g.workPath(g.binName())
+	inputs := []string{sourcePath}
+	flags := []string{"-gcflags", "-N -l"}
+	if trimPath {
+		flags = append(flags, "-trimpath")
+	}
+	if pprofPort > 0 {
+		dlog.Infof("injecting pprof listener on port %v", pprofPort)
+		overlayPath, injectedPath, err := g.pprofOverlay(sourcePath, pprofPort)
+		if err != nil {
+			return "", "", err
+		}
+		flags = append(flags, "-tags", pprofBuildTag, "-overlay", overlayPath)
+		// the overlay adds the file to a package directory, named files have to list it
+		if filepath.Ext(sourcePath) == ".go" {
+			inputs = append(inputs, injectedPath)
+		}
+	}
+	_, err = g.goCmd.Build(binSource, inputs, flags...).
 		Env(fmt.Sprintf("GOOS=%v", p.OS), fmt.Sprintf("GOARCH=%v", p.Arch), fmt.Sprintf("CGO_ENABLED=%v", 0)).Run(ctx)
 	if err != nil {
-		return err
+		return "", "", err
+	}
+	binHash, err = fileHash(binSource)
+	if err != nil {
+		return "", "", err
+	}
+	if g.unchangedBin(ctx, pod, container, binHash) {
+		dlog.Info("binary unchanged, skipping copy")
+		return binHash, buildHash, nil
 	}
 	// copy bin to pod
-	_, errCopyToPod := g.kubeCmd.CopyToPod(pod, container, binSource, g.binDestination()).Run(ctx)
-	return errCopyToPod
+	_, err = g.kubeCmd.CopyToPod(pod, container, binSource, g.binDestination()).Run(ctx)
+	return binHash, buildHash, err
 }
 
-func (g Grapple) portForwardDelve(l *logrus.Entry, ctx context.Context, pod, host string, port int) {
+// portForwardDelve forwards to the delve server until ctx is done, reconnecting broken tunnels,
+// dap servers accept a single client so their tunnel isnt probed
+func (g Grapple) portForwardDelve(l *logrus.Entry, ctx context.Context, pod, host string, localPort, podPort int, protocol string) {
 	l.Info("port-forwarding pod for delve server")
-	cmd := g.kubeCmd.PortForwardPod(pod, host, port)
-	go func() {
-		_, err := cmd.Run(ctx)
-		if err != nil && err.Error() != "signal: killed" {
-			l.WithError(err).Errorf("port-forwarding %v pod failed", pod)
-		}
-	}()
-	<-cmd.Started()
+	select {
+	case <-ctx.Done():
+	case <-g.supervisePortForward(l, ctx, pod, host, localPort, podPort, protocol != delve.ProtocolDAP):
+	}
 }
 
-func (g Grapple) checkDelveConnection(l *logrus.Entry, ctx context.Context, tries int, host string, port int) error {
+func (g Grapple) checkDelveConnection(l *logrus.Entry, ctx context.Context, tries int, host string, port int, protocol string) error {
 	time.Sleep(1 * time.Second) // allow delve to become available
 	err := tryCallWithContext(ctx, tries, 1*time.Second, func(i int) error {
 		l.Infof("connecting to %v:%v (%d/%d)", host, port, i, tries)
+		if protocol == delve.ProtocolDAP {
+			return checkDAPConnection(l, ctx, host, port)
+		}
 		dc, err := delve.NewKubeDelveClient(ctx, host, port)
 		if err != nil {
 			l.WithError(err).Warn("couldnt connect to delve server")
@@ -195,3 +382,22 @@ func (g Grapple) checkDelveConnection(l *logrus.Entry, ctx context.Context, trie
 	}
 	return err
 }
+
+func checkDAPConnection(l *logrus.Entry, ctx context.Context, host string, port int) error {
+	dc, err := delve.NewKubeDAPClient(ctx, host, port)
+	if err != nil {
+		l.WithError(err).Warn("couldnt connect to delve dap server")
+		return err
+	}
+	// the dap server exits on disconnect and gets restarted by the server loop
+	defer func() {
+		if err := dc.Close(); err != nil {
+			l.WithError(err).Warn("couldnt close delve dap client")
+		}
+	}()
+	if err := dc.Initialize(); err != nil {
+		l.WithError(err).Warn("couldnt initialize dap session with delve server")
+		return err
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"net"
 	"testing"
 
+	"github.com/foomo/gograpple/internal/delve"
 	"github.com/sirupsen/logrus"
 )
 
@@ -43,18 +44,24 @@ func TestGrapple_Delve(t *testing.T) {
 		sourcePath string
 		host       string
 		port       int
-		vscode     bool
+		ide        string
 	}
 	tests := []struct {
 		name    string
 		args    args
 		wantErr bool
 	}{
-		{"test", args{"test/app", addr.IP.String(), addr.Port, true}, false},
+		{"test", args{"test/app", addr.IP.String(), addr.Port, IDEVSCode}, false},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := g.Delve("", "", tt.args.sourcePath, nil, tt.args.host, tt.args.port, tt.args.vscode, false); (err != nil) != tt.wantErr {
+			if err := g.Delve(DelveOptions{
+				SourcePath: tt.args.sourcePath,
+				Host:       tt.args.host,
+				Port:       tt.args.port,
+				IDE:        tt.args.ide,
+				Protocol:   delve.ProtocolRPC,
+			}); (err != nil) != tt.wantErr {
 				t.Errorf("Grapple.Delve() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
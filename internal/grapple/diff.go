@@ -0,0 +1,150 @@
+package grapple
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	apps "k8s.io/api/apps/v1"
+)
+
+const (
+	diffContext = 3
+	colorGreen  = "\033[32m"
+)
+
+type diffOp int
+
+const (
+	diffEqual diffOp = iota
+	diffAdd
+	diffRemove
+)
+
+type diffLine struct {
+	op   diffOp
+	text string
+}
+
+// patchDiff renders a diff between the current deployment spec and the spec after applying patch,
+// which is computed by the api server with a dry-run
+func (g Grapple) patchDiff(ctx context.Context, patch, patchType string) (string, error) {
+	current, err := g.kubeCmd.GetDeployment(ctx, g.deployment.Name)
+	if err != nil {
+		return "", err
+	}
+	patched, err := g.kubeCmd.PatchDeploymentDryRun(ctx, patch, patchType, g.deployment.Name)
+	if err != nil {
+		return "", fmt.Errorf("couldnt dry-run deployment patch: %w", err)
+	}
+	a, err := specYAML(current)
+	if err != nil {
+		return "", err
+	}
+	b, err := specYAML(patched)
+	if err != nil {
+		return "", err
+	}
+	return formatDiff(lineDiff(a, b), diffContext, os.Getenv("NO_COLOR") == ""), nil
+}
+
+// specYAML renders the deployment spec as yaml with sorted keys, so specs can be compared line by line
+func specYAML(d *apps.Deployment) (string, error) {
+	bs, err := json.Marshal(d.Spec)
+	if err != nil {
+		return "", err
+	}
+	var spec map[string]interface{}
+	if err := json.Unmarshal(bs, &spec); err != nil {
+		return "", err
+	}
+	out, err := yaml.Marshal(spec)
+	return string(out), err
+}
+
+// lineDiff computes the lines to remove from a and add to b based on their longest common subsequence
+func lineDiff(a, b string) []diffLine {
+	as, bs := strings.Split(strings.TrimRight(a, "\n"), "\n"), strings.Split(strings.TrimRight(b, "\n"), "\n")
+	// lcs[i][j] is the length of the common subsequence of as[i:] and bs[j:]
+	lcs := make([][]int, len(as)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bs)+1)
+	}
+	for i := len(as) - 1; i >= 0; i-- {
+		for j := len(bs) - 1; j >= 0; j-- {
+			if as[i] == bs[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(as) && j < len(bs) {
+		switch {
+		case as[i] == bs[j]:
+			lines = append(lines, diffLine{diffEqual, as[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{diffRemove, as[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{diffAdd, bs[j]})
+			j++
+		}
+	}
+	for ; i < len(as); i++ {
+		lines = append(lines, diffLine{diffRemove, as[i]})
+	}
+	for ; j < len(bs); j++ {
+		lines = append(lines, diffLine{diffAdd, bs[j]})
+	}
+	return lines
+}
+
+// formatDiff prints changed lines with context lines around them, skipped lines are marked with ...
+func formatDiff(lines []diffLine, context int, color bool) string {
+	show := make([]bool, len(lines))
+	for i, l := range lines {
+		if l.op == diffEqual {
+			continue
+		}
+		for j := i - context; j <= i+context; j++ {
+			if j >= 0 && j < len(lines) {
+				show[j] = true
+			}
+		}
+	}
+	sb := new(strings.Builder)
+	skipped := false
+	for i, l := range lines {
+		if !show[i] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			sb.WriteString("...\n")
+			skipped = false
+		}
+		prefix, c := "  ", ""
+		switch l.op {
+		case diffAdd:
+			prefix, c = "+ ", colorGreen
+		case diffRemove:
+			prefix, c = "- ", colorRed
+		}
+		if color && c != "" {
+			fmt.Fprintf(sb, "%v%v%v%v\n", c, prefix, l.text, colorReset)
+		} else {
+			fmt.Fprintf(sb, "%v%v\n", prefix, l.text)
+		}
+	}
+	return sb.String()
+}
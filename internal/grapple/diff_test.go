@@ -0,0 +1,32 @@
+package grapple
+
+import "testing"
+
+func Test_formatDiff(t *testing.T) {
+	a := "replicas: 1\ntemplate:\n  spec:\n    containers:\n    - image: app:1.0\n      name: app\n      readinessProbe:\n        httpGet:\n          port: 80\n"
+	b := "replicas: 1\ntemplate:\n  spec:\n    containers:\n    - image: app-patch:latest\n      name: app\n"
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    string
+	}{
+		{"equal", a, a, 3, ""},
+		{"changes with context", a, b, 1,
+			"...\n" +
+				"      containers:\n" +
+				"-     - image: app:1.0\n" +
+				"+     - image: app-patch:latest\n" +
+				"        name: app\n" +
+				"-       readinessProbe:\n" +
+				"-         httpGet:\n" +
+				"-           port: 80\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatDiff(lineDiff(tt.a, tt.b), tt.context, false); got != tt.want {
+				t.Errorf("formatDiff() =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
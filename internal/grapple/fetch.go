@@ -0,0 +1,36 @@
+package grapple
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// Fetch copies files from the patched container into dir
+func (g Grapple) Fetch(pod, container, dir string, paths []string) error {
+	ctx := context.Background()
+	if !g.isPatched() {
+		return fmt.Errorf("deployment not patched, stopping fetch")
+	}
+	if err := g.kubeCmd.ValidatePod(ctx, g.deployment, &pod); err != nil {
+		return err
+	}
+	if err := g.kubeCmd.ValidateContainer(g.deployment, &container); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for _, p := range paths {
+		dest := filepath.Join(dir, path.Base(p))
+		g.l.Infof("fetching %v from pod %v into %v", p, pod, dest)
+		if out, err := g.kubeCmd.CopyFromPod(pod, container, p, dest).Run(ctx); err != nil {
+			return errors.WithMessage(err, out)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package grapple
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	forwardProbeInterval = 2 * time.Second
+	forwardProbeTimeout  = 500 * time.Millisecond
+	forwardMinBackoff    = 500 * time.Millisecond
+	forwardMaxBackoff    = 30 * time.Second
+)
+
+// supervisePortForward keeps a port-forward from localPort to podPort up until ctx is done,
+// a dead kubectl or a failed probe re-establishes it with backoff on the most recent pod of the deployment.
+// Probing connects to the forwarded port, so it has to be disabled for servers accepting a single client.
+// The returned channel is closed once the first port-forward started
+func (g Grapple) supervisePortForward(l *logrus.Entry, ctx context.Context, pod, host string, localPort, podPort int, probe bool) <-chan struct{} {
+	started := make(chan struct{})
+	go func() {
+		backoff := forwardMinBackoff
+		first := true
+		for ctx.Err() == nil {
+			since := time.Now()
+			err := g.runPortForward(l, ctx, pod, host, localPort, podPort, probe, func() {
+				if first {
+					first = false
+					close(started)
+				}
+			})
+			if ctx.Err() != nil {
+				return
+			}
+			if time.Since(since) > forwardMaxBackoff {
+				// it was up for a while, reconnect fast
+				backoff = forwardMinBackoff
+			}
+			l.WithError(err).Warnf("port-forward to pod %v broken, reconnecting in %v", pod, backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = nextBackoff(backoff)
+			if p, err := g.kubeCmd.GetMostRecentRunningPodBySelectors(ctx, g.deployment.Spec.Selector.MatchLabels); err != nil {
+				l.WithError(err).Warn("couldnt resolve pod, retrying the previous one")
+			} else if p != pod {
+				l.Warnf("pod %v was replaced by %v, the delve server needs a reload", pod, p)
+				pod = p
+			}
+		}
+	}()
+	return started
+}
+
+// runPortForward runs kubectl port-forward until it exits or the tunnel fails a probe
+func (g Grapple) runPortForward(l *logrus.Entry, ctx context.Context, pod, host string, localPort, podPort int, probe bool, onStarted func()) error {
+	fctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	cmd := g.kubeCmd.PortForwardPod(pod, host, localPort, podPort)
+	exited := make(chan error, 1)
+	go func() {
+		_, err := cmd.Run(fctx)
+		exited <- err
+	}()
+	select {
+	case err := <-exited:
+		return fmt.Errorf("port-forward exited: %w", err)
+	case <-cmd.Started():
+	}
+	l.Infof("port-forwarding %v:%v to pod %v port %v", host, localPort, pod, podPort)
+	onStarted()
+	ticker := time.NewTicker(forwardProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-exited:
+			return fmt.Errorf("port-forward exited: %w", err)
+		case <-ticker.C:
+			if !probe {
+				continue
+			}
+			if err := probeTunnel(host, localPort, forwardProbeTimeout); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// probeTunnel connects to the forwarded port, kubectl accepts locally but closes the connection
+// right away when the tunnel into the pod is broken, a server waiting for the client keeps it open
+func probeTunnel(host string, port int, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", fmt.Sprintf("%v:%v", host, port), timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return err
+	}
+	if _, err := conn.Read(make([]byte, 1)); err != nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+		return fmt.Errorf("tunnel closed: %w", err)
+	}
+	return nil
+}
+
+func nextBackoff(d time.Duration) time.Duration {
+	if d *= 2; d > forwardMaxBackoff {
+		return forwardMaxBackoff
+	}
+	return d
+}
@@ -0,0 +1,62 @@
+package grapple
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func Test_probeTunnel(t *testing.T) {
+	tests := []struct {
+		name    string
+		hold    bool
+		wantErr bool
+	}{
+		{"server waits for client", true, false},
+		{"tunnel closes connection", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+			go func() {
+				for {
+					conn, err := l.Accept()
+					if err != nil {
+						return
+					}
+					if !tt.hold {
+						conn.Close()
+						continue
+					}
+					defer conn.Close()
+				}
+			}()
+			err = probeTunnel("127.0.0.1", l.Addr().(*net.TCPAddr).Port, 200*time.Millisecond)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("probeTunnel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_nextBackoff(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want time.Duration
+	}{
+		{forwardMinBackoff, 2 * forwardMinBackoff},
+		{20 * time.Second, forwardMaxBackoff},
+		{forwardMaxBackoff, forwardMaxBackoff},
+	}
+	for _, tt := range tests {
+		t.Run(tt.d.String(), func(t *testing.T) {
+			if got := nextBackoff(tt.d); got != tt.want {
+				t.Errorf("nextBackoff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package grapple
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/foomo/gograpple/internal/exec"
+	"github.com/sirupsen/logrus"
+)
+
+const golandRunConfigDir = ".run"
+
+type golandOption struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type golandRunConfig struct {
+	XMLName       xml.Name `xml:"component"`
+	Name          string   `xml:"name,attr"`
+	Configuration struct {
+		Default     bool           `xml:"default,attr"`
+		Name        string         `xml:"name,attr"`
+		Type        string         `xml:"type,attr"`
+		FactoryName string         `xml:"factoryName,attr"`
+		Options     []golandOption `xml:"option"`
+		Method      struct {
+			V string `xml:"v,attr"`
+		} `xml:"method"`
+	} `xml:"configuration"`
+}
+
+func newGolandRunConfig(name, host string, port int) *golandRunConfig {
+	rc := &golandRunConfig{Name: "ProjectRunConfigurationManager"}
+	rc.Configuration.Name = name
+	rc.Configuration.Type = "GoRemoteDebugConfigurationType"
+	rc.Configuration.FactoryName = "Go Remote"
+	rc.Configuration.Options = []golandOption{
+		{"disconnectOption", "LEAVE"},
+		{"host", host},
+		{"port", fmt.Sprint(port)},
+	}
+	rc.Configuration.Method.V = "2"
+	return rc
+}
+
+// writeGolandRunConfig writes a "Go Remote" run configuration into the .run dir of the module root
+func writeGolandRunConfig(l *logrus.Entry, goModDir, deployment, host string, port int) error {
+	bs, err := xml.MarshalIndent(newGolandRunConfig(launchConfigName(deployment), host, port), "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(goModDir, golandRunConfigDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	p := filepath.Join(dir, fmt.Sprintf("gograpple-%v.run.xml", deployment))
+	if err := os.WriteFile(p, append(bs, '\n'), 0644); err != nil {
+		return err
+	}
+	l.Infof("wrote run configuration %q to %v", launchConfigName(deployment), p)
+	return nil
+}
+
+func launchGoland(ctx context.Context, l *logrus.Entry, goModDir, deployment, host string, port int) error {
+	if err := writeGolandRunConfig(l, goModDir, deployment, host, port); err != nil {
+		return err
+	}
+	cmd := exec.NewCommand("goland").Logger(l).Args(goModDir)
+	if runtime.GOOS == "darwin" {
+		cmd = exec.NewCommand("open").Logger(l).Args("-a", "GoLand", goModDir)
+	}
+	_, err := cmd.Run(ctx)
+	return err
+}
@@ -2,6 +2,8 @@ package grapple
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 
 	"github.com/foomo/gograpple/internal/exec"
 	"github.com/sirupsen/logrus"
@@ -30,6 +32,11 @@ type Grapple struct {
 	kubeCmd    *exec.KubectlCmd
 	dockerCmd  *exec.DockerCmd
 	goCmd      *exec.GoCmd
+	recorder   *exec.Recorder
+	confirm    func(diff string) (bool, error)
+	workDir    string
+	cluster    string
+	resumed    *Session
 }
 
 func NewGrapple(l *logrus.Entry, namespace, deployment string) (*Grapple, error) {
@@ -58,3 +65,30 @@ func NewGrapple(l *logrus.Entry, namespace, deployment string) (*Grapple, error)
 
 	return g, nil
 }
+
+// DryRun records mutating commands and planned artifacts into r instead of executing them
+func (g *Grapple) DryRun(r *exec.Recorder) {
+	g.recorder = r
+	g.kubeCmd.DryRun(r)
+	g.dockerCmd.DryRun(r)
+	g.goCmd.DryRun(r)
+}
+
+// WorkDir sets the local directory for the files of the session, like rendered templates and build output
+func (g *Grapple) WorkDir(dir string) {
+	g.workDir = dir
+}
+
+// workPath joins elem to the session work dir, falling back to the temp dir
+func (g Grapple) workPath(elem ...string) string {
+	dir := g.workDir
+	if dir == "" {
+		dir = os.TempDir()
+	}
+	return filepath.Join(append([]string{dir}, elem...)...)
+}
+
+// ConfirmPatch asks confirm with the spec diff before a patch is applied
+func (g *Grapple) ConfirmPatch(confirm func(diff string) (bool, error)) {
+	g.confirm = confirm
+}
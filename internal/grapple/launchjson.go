@@ -0,0 +1,330 @@
+package grapple
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	vscodeDir        = ".vscode"
+	vscodeLaunchFile = "launch.json"
+	launchVersion    = "0.2.0"
+)
+
+type launchFile struct {
+	Version        string        `json:"version"`
+	Configurations []*launchArgs `json:"configurations"`
+}
+
+// writeLaunchConfig merges la into the launch configurations of the workspace file
+// or .vscode/launch.json, entries with the same name are replaced
+func writeLaunchConfig(goModDir, workspaceFile string, la *launchArgs) (string, error) {
+	p := filepath.Join(goModDir, vscodeDir, vscodeLaunchFile)
+	keys := []string{"configurations"}
+	if workspaceFile != "" {
+		p = workspaceFile
+		keys = []string{"launch", "configurations"}
+	}
+	src, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return "", err
+		}
+		src = []byte("{}\n")
+	} else if err != nil {
+		return "", err
+	}
+	out, err := upsertLaunchConfig(src, la, keys...)
+	if err != nil {
+		return "", fmt.Errorf("couldnt update %q: %w", p, err)
+	}
+	return p, os.WriteFile(p, out, 0644)
+}
+
+// upsertLaunchConfig replaces or appends la in the configurations array found under keys,
+// the document is edited in place so comments and formatting of other entries survive
+func upsertLaunchConfig(src []byte, la *launchArgs, keys ...string) ([]byte, error) {
+	clean := sanitizeJSONC(src)
+	start, end := skipSpace(clean, 0), len(clean)
+	for i, key := range keys {
+		members, closing, err := objectMembers(clean, start, end)
+		if err != nil {
+			return nil, err
+		}
+		var member *jsonMember
+		for j := range members {
+			if members[j].key == key {
+				member = &members[j]
+				break
+			}
+		}
+		if member == nil {
+			// build whatever is missing below this object
+			kvs := []jsonKeyValue{{key, []*launchArgs{la}}}
+			if rest := keys[i+1:]; len(rest) > 0 {
+				kvs = []jsonKeyValue{{key, launchFile{Version: launchVersion, Configurations: []*launchArgs{la}}}}
+			} else if !hasMember(members, "version") {
+				// vscode expects the version next to the configurations
+				kvs = append([]jsonKeyValue{{"version", launchVersion}}, kvs...)
+			}
+			return insertMembers(src, clean, start, closing, len(members) > 0, kvs...)
+		}
+		start, end = member.valueStart, member.valueEnd
+	}
+	elems, closing, err := arrayElements(clean, start, end)
+	if err != nil {
+		return nil, err
+	}
+	for _, elem := range elems {
+		var entry struct {
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(clean[elem[0]:elem[1]], &entry); err != nil {
+			continue
+		}
+		if entry.Name == la.Name {
+			indent := lineIndent(src, elem[0])
+			value, err := json.MarshalIndent(la, indent, indentUnit(indent))
+			if err != nil {
+				return nil, err
+			}
+			return splice(src, elem[0], elem[1], value), nil
+		}
+	}
+	indent := lineIndent(src, start) + indentUnit(lineIndent(src, start))
+	if len(elems) > 0 {
+		// line up with the existing entries
+		indent = lineIndent(src, elems[len(elems)-1][0])
+	}
+	value, err := json.MarshalIndent(la, indent, indentUnit(indent))
+	if err != nil {
+		return nil, err
+	}
+	if len(elems) == 0 {
+		insert := append([]byte("\n"+indent), value...)
+		insert = append(insert, "\n"+lineIndent(src, start)...)
+		return splice(src, closing, closing, insert), nil
+	}
+	// append behind the separator and the comment trailing the last element
+	last := elems[len(elems)-1][1]
+	pos := skipInline(src, last)
+	hasComma := pos < len(src) && src[pos] == ','
+	if hasComma {
+		pos++
+	}
+	pos = skipInline(src, pos)
+	if pos+1 < len(src) && src[pos] == '/' && src[pos+1] == '/' {
+		for pos < len(src) && src[pos] != '\n' {
+			pos++
+		}
+	}
+	out := splice(src, pos, pos, append([]byte("\n"+indent), value...))
+	if !hasComma {
+		out = splice(out, last, last, []byte(","))
+	}
+	return out, nil
+}
+
+// skipInline skips spaces and block comments on the line of pos
+func skipInline(src []byte, pos int) int {
+	for pos < len(src) {
+		switch {
+		case src[pos] == ' ' || src[pos] == '\t':
+			pos++
+		case pos+1 < len(src) && src[pos] == '/' && src[pos+1] == '*':
+			end := bytes.Index(src[pos+2:], []byte("*/"))
+			if end < 0 || bytes.IndexByte(src[pos:pos+2+end], '\n') >= 0 {
+				return pos
+			}
+			pos += end + 4
+		default:
+			return pos
+		}
+	}
+	return pos
+}
+
+type jsonMember struct {
+	key        string
+	valueStart int
+	valueEnd   int
+}
+
+// objectMembers lists the members of the object in clean[start:end] and the offset of its closing brace
+func objectMembers(clean []byte, start, end int) ([]jsonMember, int, error) {
+	dec := json.NewDecoder(bytes.NewReader(clean[start:end]))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return nil, 0, fmt.Errorf("expected object at offset %d", start)
+	}
+	var members []jsonMember
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, 0, err
+		}
+		key, _ := t.(string)
+		valueStart := start + int(dec.InputOffset())
+		for valueStart < end && (isSpace(clean[valueStart]) || clean[valueStart] == ':') {
+			valueStart++
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, 0, err
+		}
+		members = append(members, jsonMember{key, valueStart, start + int(dec.InputOffset())})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, 0, err
+	}
+	return members, start + int(dec.InputOffset()) - 1, nil
+}
+
+// arrayElements lists the offsets of the elements of the array in clean[start:end]
+// and the offset of its closing bracket
+func arrayElements(clean []byte, start, end int) ([][2]int, int, error) {
+	dec := json.NewDecoder(bytes.NewReader(clean[start:end]))
+	if t, err := dec.Token(); err != nil || t != json.Delim('[') {
+		return nil, 0, fmt.Errorf("expected array at offset %d", start)
+	}
+	var elems [][2]int
+	for dec.More() {
+		elemStart := start + int(dec.InputOffset())
+		for elemStart < end && (isSpace(clean[elemStart]) || clean[elemStart] == ',') {
+			elemStart++
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, 0, err
+		}
+		elems = append(elems, [2]int{elemStart, start + int(dec.InputOffset())})
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, 0, err
+	}
+	return elems, start + int(dec.InputOffset()) - 1, nil
+}
+
+type jsonKeyValue struct {
+	key   string
+	value interface{}
+}
+
+func hasMember(members []jsonMember, key string) bool {
+	for _, m := range members {
+		if m.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func insertMembers(src, clean []byte, objStart, closing int, hasMembers bool, kvs ...jsonKeyValue) ([]byte, error) {
+	indent := lineIndent(src, objStart) + indentUnit(lineIndent(src, objStart))
+	var lines []string
+	for _, kv := range kvs {
+		bs, err := json.MarshalIndent(kv.value, indent, indentUnit(indent))
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, fmt.Sprintf("\n%v%q: %s", indent, kv.key, bs))
+	}
+	insert := strings.Join(lines, ",") + "\n" + lineIndent(src, objStart)
+	// strip the whitespace before the closing brace, it is replaced by the insert
+	pos := closing
+	for pos > objStart && isSpace(clean[pos-1]) {
+		pos--
+	}
+	if hasMembers {
+		if clean[pos-1] == ',' {
+			// already there as trailing comma
+			return splice(src, pos, closing, []byte(insert)), nil
+		}
+		return splice(src, pos, closing, []byte(","+insert)), nil
+	}
+	return splice(src, pos, closing, []byte(insert)), nil
+}
+
+// sanitizeJSONC blanks out comments and trailing commas so the result can be decoded
+// with encoding/json, all offsets stay the same as in src
+func sanitizeJSONC(src []byte) []byte {
+	clean := make([]byte, len(src))
+	copy(clean, src)
+	inString := false
+	// last significant character outside of strings and comments
+	last := -1
+	for i := 0; i < len(clean); i++ {
+		c := clean[i]
+		switch {
+		case inString:
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+				last = i
+			}
+		case c == '"':
+			inString = true
+		case c == '/' && i+1 < len(clean) && clean[i+1] == '/':
+			for ; i < len(clean) && clean[i] != '\n'; i++ {
+				clean[i] = ' '
+			}
+		case c == '/' && i+1 < len(clean) && clean[i+1] == '*':
+			clean[i], clean[i+1] = ' ', ' '
+			for i += 2; i < len(clean); i++ {
+				if clean[i] == '*' && i+1 < len(clean) && clean[i+1] == '/' {
+					clean[i], clean[i+1] = ' ', ' '
+					i++
+					break
+				}
+				if clean[i] != '\n' {
+					clean[i] = ' '
+				}
+			}
+		case isSpace(c):
+		default:
+			if (c == ']' || c == '}') && last >= 0 && clean[last] == ',' {
+				clean[last] = ' '
+			}
+			last = i
+		}
+	}
+	return clean
+}
+
+func splice(src []byte, start, end int, insert []byte) []byte {
+	out := make([]byte, 0, len(src)+len(insert))
+	out = append(out, src[:start]...)
+	out = append(out, insert...)
+	return append(out, src[end:]...)
+}
+
+func lineIndent(src []byte, pos int) string {
+	lineStart := bytes.LastIndexByte(src[:pos], '\n') + 1
+	i := lineStart
+	for i < len(src) && (src[i] == ' ' || src[i] == '\t') {
+		i++
+	}
+	return string(src[lineStart:i])
+}
+
+func indentUnit(indent string) string {
+	if strings.HasPrefix(indent, "\t") {
+		return "\t"
+	}
+	return "    "
+}
+
+func skipSpace(bs []byte, pos int) int {
+	for pos < len(bs) && isSpace(bs[pos]) {
+		pos++
+	}
+	return pos
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
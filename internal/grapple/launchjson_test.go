@@ -0,0 +1,57 @@
+package grapple
+
+import (
+	"testing"
+)
+
+func Test_upsertLaunchConfig(t *testing.T) {
+	la := &launchArgs{Name: "gograpple: example", Request: "attach", Port: 2346}
+	tests := []struct {
+		name string
+		src  string
+		keys []string
+		want string
+	}{
+		{
+			"empty",
+			"{}\n",
+			[]string{"configurations"},
+			"{\n    \"version\": \"0.2.0\",\n    \"configurations\": [\n        {\n            \"name\": \"gograpple: example\",\n            \"request\": \"attach\",\n            \"port\": 2346\n        }\n    ]\n}\n",
+		},
+		{
+			"append keeps comments",
+			"{\n    // launch configs\n    \"version\": \"0.2.0\",\n    \"configurations\": [\n        {\"name\": \"other\"}, // mine\n    ]\n}\n",
+			[]string{"configurations"},
+			"{\n    // launch configs\n    \"version\": \"0.2.0\",\n    \"configurations\": [\n        {\"name\": \"other\"}, // mine\n        {\n            \"name\": \"gograpple: example\",\n            \"request\": \"attach\",\n            \"port\": 2346\n        }\n    ]\n}\n",
+		},
+		{
+			"append without separator",
+			"{\n  \"version\": \"0.2.0\",\n  \"configurations\": [\n    {\"name\": \"other\"} /* mine */\n  ]\n}\n",
+			[]string{"configurations"},
+			"{\n  \"version\": \"0.2.0\",\n  \"configurations\": [\n    {\"name\": \"other\"}, /* mine */\n    {\n        \"name\": \"gograpple: example\",\n        \"request\": \"attach\",\n        \"port\": 2346\n    }\n  ]\n}\n",
+		},
+		{
+			"replace",
+			"{\n\t\"configurations\": [\n\t\t/* old */ {\"name\": \"gograpple: example\", \"port\": 2345},\n\t\t{\"name\": \"other\"}\n\t]\n}\n",
+			[]string{"configurations"},
+			"{\n\t\"configurations\": [\n\t\t/* old */ {\n\t\t\t\"name\": \"gograpple: example\",\n\t\t\t\"request\": \"attach\",\n\t\t\t\"port\": 2346\n\t\t},\n\t\t{\"name\": \"other\"}\n\t]\n}\n",
+		},
+		{
+			"workspace",
+			"{\n    \"folders\": [{\"path\": \".\"}],\n}\n",
+			[]string{"launch", "configurations"},
+			"{\n    \"folders\": [{\"path\": \".\"}],\n    \"launch\": {\n        \"version\": \"0.2.0\",\n        \"configurations\": [\n            {\n                \"name\": \"gograpple: example\",\n                \"request\": \"attach\",\n                \"port\": 2346\n            }\n        ]\n    }\n}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := upsertLaunchConfig([]byte(tt.src), la, tt.keys...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("upsertLaunchConfig() = \n%v\nwant\n%v", string(got), tt.want)
+			}
+		})
+	}
+}
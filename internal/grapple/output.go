@@ -0,0 +1,87 @@
+package grapple
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	outputDir    = "/tmp/gograpple/output"
+	outputStdout = "stdout"
+	outputStderr = "stderr"
+
+	colorReset = "\033[0m"
+	colorCyan  = "\033[36m"
+	colorRed   = "\033[31m"
+)
+
+// prefixWriter prefixes every complete line written to it
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix string
+	buf    []byte
+}
+
+func newPrefixWriter(w io.Writer, mu *sync.Mutex, prefix, color string) *prefixWriter {
+	if color != "" && os.Getenv("NO_COLOR") == "" {
+		prefix = color + prefix + colorReset
+	}
+	return &prefixWriter{mu: mu, w: w, prefix: prefix}
+}
+
+func (pw *prefixWriter) Write(p []byte) (int, error) {
+	pw.buf = append(pw.buf, p...)
+	for {
+		i := bytes.IndexByte(pw.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		pw.mu.Lock()
+		_, err := fmt.Fprintf(pw.w, "%v%s", pw.prefix, pw.buf[:i+1])
+		pw.mu.Unlock()
+		if err != nil {
+			return len(p), err
+		}
+		pw.buf = pw.buf[i+1:]
+	}
+}
+
+// prepareOutput creates the files dlv redirects the program output to
+func (g Grapple) prepareOutput(ctx context.Context, pod, container string) error {
+	stdout, stderr := path.Join(outputDir, outputStdout), path.Join(outputDir, outputStderr)
+	cmd := []string{"sh", "-c", fmt.Sprintf("mkdir -p %v && : > %v && : > %v", outputDir, stdout, stderr)}
+	_, err := g.kubeCmd.ExecPod(pod, container, cmd).Run(ctx)
+	return err
+}
+
+// streamOutput copies the program output into the container log and to the local terminal until ctx is done
+func (g Grapple) streamOutput(l *logrus.Entry, ctx context.Context, pod, container string) {
+	stdout, stderr := path.Join(outputDir, outputStdout), path.Join(outputDir, outputStderr)
+	// keep the output in the container log for cluster logging
+	go func() {
+		cmd := []string{"sh", "-c", fmt.Sprintf("tail -q -F -n +1 %v %v > /proc/1/fd/1", stdout, stderr)}
+		if _, err := g.kubeCmd.ExecPod(pod, container, cmd).Quiet().Run(ctx); err != nil && ctx.Err() == nil {
+			l.WithError(err).Warn("forwarding output to the container log stopped")
+		}
+	}()
+	mu := &sync.Mutex{}
+	for file, w := range map[string]io.Writer{
+		stdout: newPrefixWriter(os.Stdout, mu, fmt.Sprintf("[%v] ", g.binName()), colorCyan),
+		stderr: newPrefixWriter(os.Stderr, mu, fmt.Sprintf("[%v] ", g.binName()), colorRed),
+	} {
+		go func(file string, w io.Writer) {
+			cmd := []string{"tail", "-F", "-n", "+1", file}
+			if _, err := g.kubeCmd.ExecPod(pod, container, cmd).Quiet().Stdout(w).Run(ctx); err != nil && ctx.Err() == nil {
+				l.WithError(err).Warnf("streaming %v stopped", path.Base(file))
+			}
+		}(file, w)
+	}
+}
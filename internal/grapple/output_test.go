@@ -0,0 +1,22 @@
+package grapple
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func Test_prefixWriter(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	out := new(bytes.Buffer)
+	pw := newPrefixWriter(out, &sync.Mutex{}, "[app] ", colorCyan)
+	for _, chunk := range []string{"listening on", " :80\nrequest 1\nreq", "uest 2\n"} {
+		if _, err := pw.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "[app] listening on :80\n[app] request 1\n[app] request 2\n"
+	if out.String() != want {
+		t.Errorf("prefixWriter wrote %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,188 @@
+package grapple
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// OverlayJSON is a json patch (RFC 6902), a list of operations
+	OverlayJSON = "json"
+	// OverlayStrategic is a strategic merge patch like the deployment patch itself
+	OverlayStrategic = "strategic"
+
+	patchTypeStrategic = "strategic"
+	patchTypeMerge     = "merge"
+)
+
+// Overlay is an additive patch applied on top of the deployment patch,
+// Patch is a yaml or json template rendered with the patch values
+type Overlay struct {
+	Type  string
+	Patch string
+}
+
+// Customization replaces the embedded patch files and adds overlays to the deployment patch,
+// Vars are available in the templates as .Vars and passed to the Dockerfile as build args
+type Customization struct {
+	PatchTemplate string
+	Dockerfile    string
+	Overlays      []Overlay
+	Vars          map[string]string
+}
+
+func ValidateCustomization(c Customization) error {
+	for _, file := range []string{c.PatchTemplate, c.Dockerfile} {
+		if file == "" {
+			continue
+		}
+		if fi, err := os.Stat(file); err != nil {
+			return err
+		} else if fi.IsDir() {
+			return fmt.Errorf("%q is a directory, expected a file", file)
+		}
+	}
+	for i, o := range c.Overlays {
+		if o.Type != OverlayJSON && o.Type != OverlayStrategic {
+			return fmt.Errorf("overlay %v: invalid type %q, expected %q or %q", i, o.Type, OverlayJSON, OverlayStrategic)
+		}
+		if o.Patch == "" {
+			return fmt.Errorf("overlay %v: empty patch", i)
+		}
+	}
+	return nil
+}
+
+// renderOverlay renders the overlay template and checks its structure, the result is json
+func renderOverlay(o Overlay, values *patchValues) (string, error) {
+	tpl, err := template.New(o.Type).Option("missingkey=error").Parse(o.Patch)
+	if err != nil {
+		return "", err
+	}
+	buf := new(bytes.Buffer)
+	if err := tpl.Execute(buf, values); err != nil {
+		return "", err
+	}
+	var patch interface{}
+	if err := yaml.Unmarshal(buf.Bytes(), &patch); err != nil {
+		return "", err
+	}
+	switch o.Type {
+	case OverlayJSON:
+		ops, ok := patch.([]interface{})
+		if !ok {
+			return "", fmt.Errorf("json patch must be a list of operations")
+		}
+		for i, op := range ops {
+			m, ok := op.(map[string]interface{})
+			if !ok || m["op"] == nil || m["path"] == nil {
+				return "", fmt.Errorf("json patch operation %v needs an op and a path", i)
+			}
+		}
+	case OverlayStrategic:
+		if _, ok := patch.(map[string]interface{}); !ok {
+			return "", fmt.Errorf("strategic merge patch must be a map")
+		}
+	}
+	bs, err := json.Marshal(patch)
+	if err != nil {
+		return "", err
+	}
+	return string(bs), nil
+}
+
+// applyOverlays applies the overlays locally on the deployment the api server would store after the patch,
+// the result is returned as a single merge patch against the current deployment
+func (g Grapple) applyOverlays(ctx context.Context, patch string, overlays []Overlay, values *patchValues) (string, error) {
+	current, err := g.kubeCmd.GetDeployment(ctx, g.deployment.Name)
+	if err != nil {
+		return "", err
+	}
+	patched, err := g.kubeCmd.PatchDeploymentDryRun(ctx, patch, patchTypeStrategic, g.deployment.Name)
+	if err != nil {
+		return "", fmt.Errorf("couldnt dry-run deployment patch: %w", err)
+	}
+	bs, err := json.Marshal(patched)
+	if err != nil {
+		return "", err
+	}
+	file := g.workPath(g.binName() + "-overlay-deployment.json")
+	for i, o := range overlays {
+		rendered, err := renderOverlay(o, values)
+		if err != nil {
+			return "", fmt.Errorf("overlay %v: %w", i, err)
+		}
+		if g.recorder != nil {
+			g.recorder.Add(fmt.Sprintf("deployment %v %v overlay %v", g.deployment.Name, o.Type, i), rendered)
+		}
+		if err := os.WriteFile(file, bs, 0600); err != nil {
+			return "", err
+		}
+		out, err := g.kubeCmd.PatchFile(ctx, file, rendered, o.Type)
+		if err != nil {
+			return "", fmt.Errorf("overlay %v: %w", i, err)
+		}
+		bs = []byte(out)
+	}
+	from, err := patchableFields(current)
+	if err != nil {
+		return "", err
+	}
+	to, err := patchableFields(json.RawMessage(bs))
+	if err != nil {
+		return "", err
+	}
+	mp, err := json.Marshal(mergePatch(from, to))
+	if err != nil {
+		return "", err
+	}
+	return string(mp), nil
+}
+
+// patchableFields keeps the annotations and the spec of a deployment, the rest is owned by the api server
+func patchableFields(d interface{}) (map[string]interface{}, error) {
+	bs, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(bs, &m); err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{"spec": m["spec"]}
+	if meta, ok := m["metadata"].(map[string]interface{}); ok && meta["annotations"] != nil {
+		fields["metadata"] = map[string]interface{}{"annotations": meta["annotations"]}
+	}
+	return fields, nil
+}
+
+// mergePatch creates a json merge patch (RFC 7386) turning from into to,
+// removed keys are set to null and lists are replaced as a whole
+func mergePatch(from, to map[string]interface{}) map[string]interface{} {
+	patch := map[string]interface{}{}
+	for k := range from {
+		if _, ok := to[k]; !ok {
+			patch[k] = nil
+		}
+	}
+	for k, v := range to {
+		fromMap, fromOk := from[k].(map[string]interface{})
+		toMap, toOk := v.(map[string]interface{})
+		switch {
+		case fromOk && toOk:
+			if p := mergePatch(fromMap, toMap); len(p) > 0 {
+				patch[k] = p
+			}
+		case !reflect.DeepEqual(from[k], v):
+			patch[k] = v
+		}
+	}
+	return patch
+}
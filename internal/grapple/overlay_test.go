@@ -0,0 +1,59 @@
+package grapple
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_renderOverlay(t *testing.T) {
+	values := &patchValues{Container: "app", Vars: map[string]string{"cpu": "2"}}
+	tests := []struct {
+		name    string
+		overlay Overlay
+		want    string
+		wantErr bool
+	}{
+		{"strategic", Overlay{OverlayStrategic, "spec:\n  template:\n    spec:\n      containers:\n      - name: {{ .Container }}\n        resources:\n          limits:\n            cpu: \"{{ .Vars.cpu }}\"\n"},
+			`{"spec":{"template":{"spec":{"containers":[{"name":"app","resources":{"limits":{"cpu":"2"}}}]}}}}`, false},
+		{"json", Overlay{OverlayJSON, `[{"op": "add", "path": "/spec/template/spec/containers/0/securityContext", "value": {"capabilities": {"add": ["SYS_PTRACE"]}}}]`},
+			`[{"op":"add","path":"/spec/template/spec/containers/0/securityContext","value":{"capabilities":{"add":["SYS_PTRACE"]}}}]`, false},
+		{"json without path", Overlay{OverlayJSON, `[{"op": "remove"}]`}, "", true},
+		{"json not a list", Overlay{OverlayJSON, `{"op": "remove", "path": "/spec"}`}, "", true},
+		{"strategic not a map", Overlay{OverlayStrategic, `- spec`}, "", true},
+		{"missing var", Overlay{OverlayStrategic, `spec: {{ .Vars.missing }}`}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderOverlay(tt.overlay, values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("renderOverlay() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("renderOverlay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_mergePatch(t *testing.T) {
+	tests := []struct {
+		name string
+		from map[string]interface{}
+		to   map[string]interface{}
+		want map[string]interface{}
+	}{
+		{"equal", map[string]interface{}{"a": 1.0}, map[string]interface{}{"a": 1.0}, map[string]interface{}{}},
+		{"removed key", map[string]interface{}{"a": 1.0, "b": 2.0}, map[string]interface{}{"a": 1.0}, map[string]interface{}{"b": nil}},
+		{"nested", map[string]interface{}{"a": map[string]interface{}{"b": 1.0, "c": 2.0}}, map[string]interface{}{"a": map[string]interface{}{"b": 1.0, "c": 3.0}},
+			map[string]interface{}{"a": map[string]interface{}{"c": 3.0}}},
+		{"list replaced", map[string]interface{}{"a": []interface{}{1.0, 2.0}}, map[string]interface{}{"a": []interface{}{1.0}},
+			map[string]interface{}{"a": []interface{}{1.0}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergePatch(tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergePatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"time"
 
+	"github.com/foomo/gograpple/internal/lifecycle"
 	"github.com/foomo/gograpple/util"
 	"github.com/pkg/errors"
 )
@@ -18,6 +20,7 @@ var (
 	bindata embed.FS
 )
 
+// Mount is a local dir synced into an emptyDir volume mounted at MountPath
 type Mount struct {
 	HostPath  string
 	MountPath string
@@ -31,6 +34,13 @@ type patchValues struct {
 	ConfigMapMount string
 	Mounts         []Mount
 	Image          string
+	RunAsUser      string
+	RunAsGroup     string
+	Probes         string
+	ProbeContainer string
+	ProbePort      int
+	HaltedMarker   string
+	Vars           map[string]string
 }
 
 func (g Grapple) newPatchValues(deployment, container, image string, mounts []Mount) *patchValues {
@@ -42,11 +52,35 @@ func (g Grapple) newPatchValues(deployment, container, image string, mounts []Mo
 		ConfigMapMount: defaultConfigMapMount,
 		Mounts:         mounts,
 		Image:          image,
+		Probes:         ProbesDrop,
+		ProbeContainer: probeContainerName,
+		HaltedMarker:   haltedMarker,
 	}
 }
 
-func (g Grapple) Patch(image, container string, mounts []Mount) error {
-	ctx := context.Background()
+// Patch replaces the container with the patch image, probes is one of ProbesDrop, ProbesProxy or ProbesBreakpoint,
+// custom replaces the embedded patch files and adds overlays
+func (g Grapple) Patch(image, container string, mounts []Mount, probes string, custom Customization) error {
+	// a shutdown stops patching before the registered rollback runs, so they dont race
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	defer close(done)
+	defer lifecycle.Register("stop patching", time.Minute, func(stepCtx context.Context) error {
+		cancel()
+		select {
+		case <-done:
+			return nil
+		case <-stepCtx.Done():
+			return stepCtx.Err()
+		}
+	})()
+	if err := ValidateProbes(probes); err != nil {
+		return err
+	}
+	if err := ValidateCustomization(custom); err != nil {
+		return err
+	}
 	if g.isPatched() {
 		g.l.Warn("deployment already patched, rolling back first")
 		if err := g.rollback(ctx); err != nil {
@@ -56,21 +90,20 @@ func (g Grapple) Patch(image, container string, mounts []Mount) error {
 	if err := g.kubeCmd.ValidateContainer(g.deployment, &container); err != nil {
 		return err
 	}
-
-	g.l.Infof("creating a configmap with deployment data")
-	bs, err := json.Marshal(g.deployment)
-	if err != nil {
-		return err
-	}
-	_, _ = g.kubeCmd.DeleteConfigMap(g.DeploymentConfigMapName()).Quiet().Run(ctx)
-	data := map[string]string{defaultConfigMapDeploymentKey: string(bs)}
-	_, err = g.kubeCmd.CreateConfigMap(g.DeploymentConfigMapName(), data).Run(ctx)
-	if err != nil {
-		return err
+	probePortValue := 0
+	if probes == ProbesProxy {
+		c, err := g.kubeCmd.GetContainerFromDeployment(container, &g.deployment)
+		if err != nil {
+			return err
+		}
+		if probePortValue, err = probePort(c); err != nil {
+			return err
+		}
+		g.l.Infof("readiness will be reported by the %v sidecar checking port %v", probeContainerName, probePortValue)
 	}
 
 	g.l.Infof("waiting for deployment to get ready")
-	_, err = g.kubeCmd.WaitForRollout(g.deployment.Name, defaultWaitTimeout).Run(ctx)
+	_, err := g.kubeCmd.WaitForRollout(g.deployment.Name, defaultWaitTimeout).Run(ctx)
 	if err != nil {
 		return err
 	}
@@ -84,16 +117,16 @@ func (g Grapple) Patch(image, container string, mounts []Mount) error {
 		perm           = 0700
 	)
 
-	patchDockerfile, err := bindata.ReadFile(filepath.Join(patchFolder, dockerfileName))
+	patchDockerfile, err := readPatchFile(custom.Dockerfile, filepath.Join(patchFolder, dockerfileName))
 	if err != nil {
 		return err
 	}
-	deploymentPatch, err := bindata.ReadFile(filepath.Join(patchFolder, patchFileName))
+	deploymentPatch, err := readPatchFile(custom.PatchTemplate, filepath.Join(patchFolder, patchFileName))
 	if err != nil {
 		return err
 	}
 
-	theHookPath := path.Join(os.TempDir(), patchFolder)
+	theHookPath := g.workPath(patchFolder)
 	_ = os.Mkdir(theHookPath, perm)
 	err = os.WriteFile(filepath.Join(theHookPath, dockerfileName), patchDockerfile, perm)
 	if err != nil {
@@ -124,12 +157,76 @@ func (g Grapple) Patch(image, container string, mounts []Mount) error {
 	if err != nil {
 		return err
 	}
+	// keep the user of the deployment image, the patch image is built from another base
+	runAsUser, runAsGroup := g.imageUser(ctx, container, deploymentImage)
 
 	pathedImageName := g.patchedImageName(imageRepo)
+	if g.recorder != nil {
+		g.recorder.Add("image", fmt.Sprintf("%v:%v from %v for %v", pathedImageName, defaultTag, image, deploymentImage))
+	}
+	g.l.Infof("rendering deployment patch template")
+	values := g.newPatchValues(g.deployment.Name, container, fmt.Sprintf("%v:%v", pathedImageName, defaultTag), mounts)
+	values.RunAsUser, values.RunAsGroup = runAsUser, runAsGroup
+	values.Probes, values.ProbePort = probes, probePortValue
+	values.Vars = custom.Vars
+	patch, err := renderTemplate(path.Join(theHookPath, devDeploymentPatchFile), values)
+	if err != nil {
+		return err
+	}
+
+	if g.recorder != nil {
+		g.recorder.Add(fmt.Sprintf("deployment %v patch", g.deployment.Name), patch)
+	}
+	patchType := patchTypeStrategic
+	if len(custom.Overlays) > 0 {
+		g.l.Infof("applying %v patch overlays", len(custom.Overlays))
+		if patch, err = g.applyOverlays(ctx, patch, custom.Overlays, values); err != nil {
+			return err
+		}
+		patchType = patchTypeMerge
+		if g.recorder != nil {
+			g.recorder.Add(fmt.Sprintf("deployment %v merge patch with overlays", g.deployment.Name), patch)
+		}
+	}
+	// the dry-run of the diff validates the patch before anything is changed
+	diff, err := g.patchDiff(ctx, patch, patchType)
+	if err != nil {
+		return err
+	}
+	if g.recorder != nil {
+		g.recorder.Add(fmt.Sprintf("deployment %v spec diff", g.deployment.Name), diff)
+	} else if g.confirm != nil {
+		ok, err := g.confirm(diff)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("patch of deployment %v not confirmed", g.deployment.Name)
+		}
+	}
+
+	g.l.Infof("creating a configmap with deployment data")
+	bs, err := json.Marshal(g.deployment)
+	if err != nil {
+		return err
+	}
+	_, _ = g.kubeCmd.DeleteConfigMap(g.DeploymentConfigMapName()).Quiet().Run(ctx)
+	data := map[string]string{defaultConfigMapDeploymentKey: string(bs)}
+	if g.recorder != nil {
+		g.recorder.Add(fmt.Sprintf("configmap %v key %v", g.DeploymentConfigMapName(), defaultConfigMapDeploymentKey), string(bs))
+	}
+	_, err = g.kubeCmd.CreateConfigMap(g.DeploymentConfigMapName(), data).Run(ctx)
+	if err != nil {
+		return err
+	}
+
 	g.l.Infof("building patch image %v:%v", pathedImageName, defaultTag)
-	if out, err := g.dockerCmd.Build(theHookPath, "--build-arg",
-		fmt.Sprintf("IMAGE=%v", image), "-t", fmt.Sprintf("%v:%v", pathedImageName, defaultTag),
-		"--platform", deploymentPlatform.String()).Quiet().Run(ctx); err != nil {
+	buildArgs := []string{"--build-arg", fmt.Sprintf("IMAGE=%v", image)}
+	for _, k := range sortedKeys(custom.Vars) {
+		buildArgs = append(buildArgs, "--build-arg", fmt.Sprintf("%v=%v", k, custom.Vars[k]))
+	}
+	if out, err := g.dockerCmd.Build(theHookPath, append(buildArgs, "-t", fmt.Sprintf("%v:%v", pathedImageName, defaultTag),
+		"--platform", deploymentPlatform.String())...).Quiet().Run(ctx); err != nil {
 		return errors.WithMessage(err, out)
 	}
 
@@ -142,29 +239,40 @@ func (g Grapple) Patch(image, container string, mounts []Mount) error {
 		}
 	}
 
-	g.l.Infof("rendering deployment patch template")
-	patch, err := renderTemplate(
-		path.Join(theHookPath, devDeploymentPatchFile),
-		g.newPatchValues(g.deployment.Name, container, fmt.Sprintf("%v:%v", pathedImageName, defaultTag), mounts),
-	)
-	if err != nil {
-		return err
-	}
-
 	g.l.Infof("patching deployment %s for development with patch", g.deployment.Name)
-	_, err = g.kubeCmd.PatchDeployment(patch, g.deployment.Name).Run(ctx)
+	_, err = g.kubeCmd.PatchDeployment(patch, patchType, g.deployment.Name).Run(ctx)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (g *Grapple) Rollback() error {
+// readPatchFile reads the user supplied file or the embedded one if file is empty
+func readPatchFile(file, embedded string) ([]byte, error) {
+	if file != "" {
+		return os.ReadFile(file)
+	}
+	return bindata.ReadFile(embedded)
+}
+
+func (g *Grapple) Rollback(ctx context.Context) error {
 	g.l.Info("rolling back")
 	if !g.isPatched() {
 		return fmt.Errorf("deployment not patched, stopping rollback")
 	}
-	return g.rollback(context.Background())
+	return g.rollback(ctx)
+}
+
+// Undo removes what a patch left behind, a patched deployment is rolled back and the configmap
+// of an interrupted patch is removed, it is registered before patching so a shutdown cleans up
+func (g *Grapple) Undo(ctx context.Context) error {
+	if g.isPatched() {
+		g.l.Info("rolling back")
+		return g.rollback(ctx)
+	}
+	// may not exist
+	_, _ = g.kubeCmd.DeleteConfigMap(g.DeploymentConfigMapName()).Quiet().Run(ctx)
+	return nil
 }
 
 func (g Grapple) isPatched() bool {
@@ -192,6 +300,10 @@ func (g Grapple) rollback(ctx context.Context) error {
 		if _, err = g.kubeCmd.RolloutUndo(g.deployment.Name, i).Run(ctx); err != nil {
 			return err
 		}
+		if g.recorder != nil {
+			// nothing was rolled back, the state cant be checked
+			return nil
+		}
 		if !g.isPatched() {
 			// annotate rollback
 			if _, err = g.kubeCmd.UpdateChangeCause(g.deployment.Name, fmt.Sprintf("rollback to %v", i)).Run(ctx); err != nil {
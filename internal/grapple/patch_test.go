@@ -21,7 +21,7 @@ func TestGrapple_Patch(t *testing.T) {
 	g := testGrapple(t, "example")
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if err := g.Patch(tt.args.dockerfile, tt.args.container, tt.args.mounts); (err != nil) != tt.wantErr {
+			if err := g.Patch(tt.args.dockerfile, tt.args.container, tt.args.mounts, ProbesDrop, Customization{}); (err != nil) != tt.wantErr {
 				t.Errorf("Grapple.Patch() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
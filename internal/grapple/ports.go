@@ -0,0 +1,48 @@
+package grapple
+
+import (
+	core "k8s.io/api/core/v1"
+)
+
+// defaultPodDelvePort is the first port tried for the delve server in the pod
+const defaultPodDelvePort = 2345
+
+// allocatePorts picks a free local port and a pod port that doesnt collide with the ports of the deployment,
+// reserved pod ports like the injected pprof port are skipped as well, a port other than 0 is used on both sides
+func (g Grapple) allocatePorts(host string, port int, reserved ...int) (localPort, podPort int, err error) {
+	if port != 0 {
+		return port, port, nil
+	}
+	if g.resumed != nil && g.resumed.LocalPort != 0 {
+		g.l.Infof("reusing local port %v forwarding to pod port %v", g.resumed.LocalPort, g.resumed.PodPort)
+		return g.resumed.LocalPort, g.resumed.PodPort, nil
+	}
+	if localPort, err = FindFreePort(host); err != nil {
+		return 0, 0, err
+	}
+	podPort = freePodPort(g.deployment.Spec.Template.Spec, defaultPodDelvePort, reserved...)
+	g.l.Infof("allocated local port %v forwarding to pod port %v", localPort, podPort)
+	return localPort, podPort, nil
+}
+
+// freePodPort returns the first port from on, that is not used by a container port or probe of spec
+func freePodPort(spec core.PodSpec, from int, reserved ...int) int {
+	used := map[int]bool{}
+	for _, port := range reserved {
+		used[port] = true
+	}
+	for _, c := range append(spec.InitContainers, spec.Containers...) {
+		c := c
+		for _, p := range c.Ports {
+			used[int(p.ContainerPort)] = true
+		}
+		if port, err := probePort(&c); err == nil {
+			used[port] = true
+		}
+	}
+	port := from
+	for used[port] {
+		port++
+	}
+	return port
+}
@@ -0,0 +1,32 @@
+package grapple
+
+import (
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func Test_freePodPort(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     core.PodSpec
+		reserved []int
+		want     int
+	}{
+		{"no ports", core.PodSpec{Containers: []core.Container{{Name: "app"}}}, nil, 2345},
+		{"container port", core.PodSpec{Containers: []core.Container{{Name: "app", Ports: []core.ContainerPort{{ContainerPort: 2345}}}}}, nil, 2346},
+		{"sidecar and probe ports", core.PodSpec{Containers: []core.Container{
+			{Name: "app", Ports: []core.ContainerPort{{ContainerPort: 2345}}},
+			{Name: "proxy", ReadinessProbe: &core.Probe{ProbeHandler: core.ProbeHandler{TCPSocket: &core.TCPSocketAction{Port: intstr.FromInt(2346)}}}},
+		}}, nil, 2347},
+		{"reserved", core.PodSpec{Containers: []core.Container{{Name: "app"}}}, []int{2345}, 2346},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := freePodPort(tt.spec, defaultPodDelvePort, tt.reserved...); got != tt.want {
+				t.Errorf("freePodPort() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package grapple
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/foomo/gograpple/internal/delve"
+	"github.com/sirupsen/logrus"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// ProbesDrop removes all probes, the patched pod is always ready
+	ProbesDrop = "drop"
+	// ProbesProxy moves the readiness probe to a sidecar, that is ready while the app listens
+	ProbesProxy = "proxy"
+	// ProbesBreakpoint makes the pod NotReady while the debugged process is halted
+	ProbesBreakpoint = "breakpoint"
+
+	probesAnnotation   = "gograpple/probes"
+	probeContainerName = "gograpple-probe"
+	haltedMarker       = "/tmp/gograpple-halted"
+)
+
+func ValidateProbes(strategy string) error {
+	switch strategy {
+	case ProbesDrop, ProbesProxy, ProbesBreakpoint:
+		return nil
+	}
+	return fmt.Errorf("invalid probes strategy %q, expected one of %q, %q or %q", strategy, ProbesDrop, ProbesProxy, ProbesBreakpoint)
+}
+
+// probePort resolves the port checked by the probes of c, falling back to the first container port
+func probePort(c *core.Container) (int, error) {
+	for _, probe := range []*core.Probe{c.ReadinessProbe, c.LivenessProbe, c.StartupProbe} {
+		if probe == nil {
+			continue
+		}
+		switch {
+		case probe.HTTPGet != nil:
+			return resolvePort(c, probe.HTTPGet.Port)
+		case probe.TCPSocket != nil:
+			return resolvePort(c, probe.TCPSocket.Port)
+		case probe.GRPC != nil:
+			return int(probe.GRPC.Port), nil
+		}
+	}
+	if len(c.Ports) > 0 {
+		return int(c.Ports[0].ContainerPort), nil
+	}
+	return 0, fmt.Errorf("no probe or container port found on container %q", c.Name)
+}
+
+// resolvePort resolves named ports, they can not be used by the probe sidecar
+func resolvePort(c *core.Container, port intstr.IntOrString) (int, error) {
+	if port.Type == intstr.Int {
+		return port.IntValue(), nil
+	}
+	for _, p := range c.Ports {
+		if p.Name == port.StrVal {
+			return int(p.ContainerPort), nil
+		}
+	}
+	return 0, fmt.Errorf("named port %q not found on container %q", port.StrVal, c.Name)
+}
+
+// probesStrategy returns the strategy the deployment got patched with
+func (g Grapple) probesStrategy(ctx context.Context) string {
+	d, err := g.kubeCmd.GetDeployment(ctx, g.deployment.Name)
+	if err != nil {
+		return ProbesDrop
+	}
+	if strategy, ok := d.Spec.Template.Annotations[probesAnnotation]; ok {
+		return strategy
+	}
+	return ProbesDrop
+}
+
+// waitForProbeSidecar waits for a running pod with the probe sidecar,
+// the rollout wont finish before the debugged app listens
+func (g Grapple) waitForProbeSidecar(ctx context.Context, tries int) error {
+	return tryCallWithContext(ctx, tries, time.Second, func(i int) error {
+		pod, err := g.kubeCmd.GetMostRecentRunningPodBySelectors(ctx, g.deployment.Spec.Selector.MatchLabels)
+		if err != nil {
+			return err
+		}
+		containers, err := g.kubeCmd.GetPodContainers(ctx, pod)
+		if err != nil {
+			return err
+		}
+		if !stringIsInSlice(probeContainerName, containers) {
+			return fmt.Errorf("pod %v is not patched yet", pod)
+		}
+		return nil
+	})
+}
+
+// watchHalted marks the pod NotReady while the debugged process is halted, until ctx is done
+func (g Grapple) watchHalted(l *logrus.Entry, ctx context.Context, pod, container, host string, port int) {
+	dc, err := delve.NewKubeDelveClient(ctx, host, port)
+	if err != nil {
+		l.WithError(err).Error("couldnt connect to delve server to watch for breakpoints")
+		return
+	}
+	defer dc.Close()
+	halted := false
+	setHalted := func(h bool) {
+		cmd := []string{"rm", "-f", haltedMarker}
+		if h {
+			cmd = []string{"touch", haltedMarker}
+		}
+		if _, err := g.kubeCmd.ExecPod(pod, container, cmd).Quiet().Run(context.Background()); err != nil {
+			l.WithError(err).Warn("couldnt update readiness")
+			return
+		}
+		halted = h
+		if h {
+			l.Info("process halted, pod is NotReady")
+		} else {
+			l.Info("process running, pod is Ready")
+		}
+	}
+	defer func() {
+		if halted {
+			setHalted(false)
+		}
+	}()
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			state, err := dc.GetStateNonBlocking()
+			if err != nil {
+				l.WithError(err).Warn("couldnt get delve state")
+				continue
+			}
+			if state.Exited {
+				return
+			}
+			if isHalted := !state.Running; isHalted != halted {
+				setHalted(isHalted)
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package grapple
+
+import (
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func Test_probePort(t *testing.T) {
+	ports := []core.ContainerPort{{Name: "http", ContainerPort: 8080}, {Name: "metrics", ContainerPort: 9100}}
+	httpProbe := func(port intstr.IntOrString) *core.Probe {
+		return &core.Probe{ProbeHandler: core.ProbeHandler{HTTPGet: &core.HTTPGetAction{Port: port}}}
+	}
+	tests := []struct {
+		name      string
+		container core.Container
+		want      int
+		wantErr   bool
+	}{
+		{"readiness port", core.Container{Ports: ports, ReadinessProbe: httpProbe(intstr.FromInt(8081))}, 8081, false},
+		{"named port", core.Container{Ports: ports, ReadinessProbe: httpProbe(intstr.FromString("metrics"))}, 9100, false},
+		{"liveness tcp", core.Container{LivenessProbe: &core.Probe{ProbeHandler: core.ProbeHandler{TCPSocket: &core.TCPSocketAction{Port: intstr.FromInt(5000)}}}}, 5000, false},
+		{"first container port", core.Container{Ports: ports}, 8080, false},
+		{"unknown named port", core.Container{ReadinessProbe: httpProbe(intstr.FromString("http"))}, 0, true},
+		{"no port", core.Container{}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := probePort(&tt.container)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("probePort() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("probePort() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
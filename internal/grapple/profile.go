@@ -0,0 +1,164 @@
+package grapple
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/foomo/gograpple/internal/exec"
+)
+
+const (
+	pprofBuildTag      = "gograpple_pprof"
+	pprofTemplate      = "the-hook/pprof.go.tmpl"
+	pprofInjectedFile  = "zz_gograpple_pprof.go"
+	DefaultPprofPort   = 6060
+	profileTrace       = "trace"
+	profileCPU         = "cpu"
+	defaultProfileHost = "127.0.0.1"
+)
+
+var profiles = []string{profileCPU, "heap", "goroutine", "allocs", "block", "mutex", "threadcreate", profileTrace}
+
+func ValidateProfile(profile string) error {
+	if !stringIsInSlice(profile, profiles) {
+		return fmt.Errorf("invalid profile %q, available: %v", profile, strings.Join(profiles, ", "))
+	}
+	return nil
+}
+
+// pprofOverlay writes the pprof listener into a go build overlay, so it is compiled into
+// the main package next to sourcePath without touching the local sources
+func (g Grapple) pprofOverlay(sourcePath string, port int) (overlayPath, injectedPath string, err error) {
+	tplData, err := bindata.ReadFile(pprofTemplate)
+	if err != nil {
+		return "", "", err
+	}
+	tpl, err := template.New(path.Base(pprofTemplate)).Parse(string(tplData))
+	if err != nil {
+		return "", "", err
+	}
+	buf := new(bytes.Buffer)
+	if err := tpl.Execute(buf, struct{ Port int }{port}); err != nil {
+		return "", "", err
+	}
+	src := g.workPath(g.binName() + "-" + pprofInjectedFile)
+	if err := os.WriteFile(src, buf.Bytes(), 0600); err != nil {
+		return "", "", err
+	}
+	absSourcePath, err := filepath.Abs(sourcePath)
+	if err != nil {
+		return "", "", err
+	}
+	// the file joins the main package, next to a single source file or inside the package directory
+	pkgDir := absSourcePath
+	if info, err := os.Stat(absSourcePath); err == nil && !info.IsDir() {
+		pkgDir = filepath.Dir(absSourcePath)
+	}
+	injectedPath = filepath.Join(pkgDir, pprofInjectedFile)
+	overlay, err := json.Marshal(map[string]map[string]string{"Replace": {injectedPath: src}})
+	if err != nil {
+		return "", "", err
+	}
+	overlayPath = g.workPath(g.binName() + "-overlay.json")
+	return overlayPath, injectedPath, os.WriteFile(overlayPath, overlay, 0600)
+}
+
+// Profile port-forwards the pprof port of the container and saves the given profiles into outDir,
+// cpu and trace profiles are collected for duration
+func (g Grapple) Profile(pod, container string, pprofPort int, names []string, duration time.Duration, outDir string, open bool) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, name := range names {
+		if err := ValidateProfile(name); err != nil {
+			return err
+		}
+	}
+	if err := g.kubeCmd.ValidatePod(ctx, g.deployment, &pod); err != nil {
+		return err
+	}
+	if err := g.kubeCmd.ValidateContainer(g.deployment, &container); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+	// the pprof port may already be taken locally, e.g. by another session
+	localPort, err := FindFreePort(defaultProfileHost)
+	if err != nil {
+		return err
+	}
+	plog := g.componentLog("profile")
+	plog.Infof("port-forwarding %v:%v to pod pprof port %v", defaultProfileHost, localPort, pprofPort)
+	pfCmd := g.kubeCmd.PortForwardPod(pod, defaultProfileHost, localPort, pprofPort)
+	go func() {
+		if _, err := pfCmd.Run(ctx); err != nil && ctx.Err() == nil {
+			plog.WithError(err).Errorf("port-forwarding %v pod failed", pod)
+		}
+	}()
+	<-pfCmd.Started()
+	var files []string
+	for _, name := range names {
+		file, err := g.fetchProfile(ctx, name, localPort, duration, outDir)
+		if err != nil {
+			return err
+		}
+		plog.Infof("saved %v profile to %v", name, file)
+		files = append(files, file)
+	}
+	if open && len(files) > 0 {
+		tool := []string{"tool", "pprof", "-http=:", files[0]}
+		if names[0] == profileTrace {
+			tool = []string{"tool", "trace", files[0]}
+		}
+		_, err := exec.NewCommand("go").Logger(plog).Args(tool...).Run(ctx)
+		return err
+	}
+	return nil
+}
+
+func (g Grapple) fetchProfile(ctx context.Context, name string, port int, duration time.Duration, outDir string) (string, error) {
+	url := fmt.Sprintf("http://%v:%v/debug/pprof/%v", defaultProfileHost, port, name)
+	file := filepath.Join(outDir, name+".pprof")
+	switch name {
+	case profileCPU:
+		url = fmt.Sprintf("http://%v:%v/debug/pprof/profile?seconds=%d", defaultProfileHost, port, int(duration.Seconds()))
+	case profileTrace:
+		url = fmt.Sprintf("%v?seconds=%d", url, int(duration.Seconds()))
+		file = filepath.Join(outDir, name+".out")
+	}
+	g.componentLog("profile").Infof("collecting %v profile", name)
+	var resp *http.Response
+	err := tryCallWithContext(ctx, 5, time.Second, func(i int) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err = http.DefaultClient.Do(req)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("couldnt get %v profile: %v %v", name, resp.Status, string(msg))
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, resp.Body)
+	return file, err
+}
@@ -0,0 +1,178 @@
+package grapple
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/foomo/gograpple/internal/exec"
+	core "k8s.io/api/core/v1"
+)
+
+// runSpec describes how the original container started its process
+type runSpec struct {
+	// Command is the resolved entrypoint including its arguments
+	Command    []string
+	WorkingDir string
+	// Env holds the image env that is not set by the container spec,
+	// it was lost when the image got replaced by the patch image
+	Env []string
+}
+
+// newRunSpec resolves command, working dir and env the same way the kubelet does,
+// specKeys are the env names the container spec defines through env and envFrom
+func newRunSpec(c *core.Container, ic *exec.ImageConfig, specKeys []string) runSpec {
+	if ic == nil {
+		ic = &exec.ImageConfig{}
+	}
+	var rs runSpec
+	switch {
+	case len(c.Command) > 0:
+		// the image cmd is ignored once the command is overridden
+		rs.Command = append(append(rs.Command, c.Command...), c.Args...)
+	case len(c.Args) > 0:
+		rs.Command = append(append(rs.Command, ic.Entrypoint...), c.Args...)
+	default:
+		rs.Command = append(append(rs.Command, ic.Entrypoint...), ic.Cmd...)
+	}
+	rs.WorkingDir = c.WorkingDir
+	if rs.WorkingDir == "" {
+		rs.WorkingDir = ic.WorkingDir
+	}
+	for _, env := range ic.Env {
+		key, _, _ := strings.Cut(env, "=")
+		if !stringIsInSlice(key, specKeys) {
+			rs.Env = append(rs.Env, env)
+		}
+	}
+	return rs
+}
+
+// split finds the application binary in the command by its path or base name, names are tried in order.
+// The entries before it are the wrapper running the binary, like an entrypoint script, and the ones after it its args.
+// If no name matches, the first entry is taken as the binary so its args are kept
+func (rs runSpec) split(names ...string) (wrapper, args []string, ok bool) {
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		for i, entry := range rs.Command {
+			if entry == name || path.Base(entry) == path.Base(name) {
+				return rs.Command[:i], rs.Command[i+1:], true
+			}
+		}
+	}
+	if len(rs.Command) > 0 {
+		return nil, rs.Command[1:], false
+	}
+	return nil, nil, false
+}
+
+// binaryNames are the names the application binary is expected to have, the name go build gives
+// the main package of sourcePath, the deployment and the container
+func (g Grapple) binaryNames(sourcePath, container string) []string {
+	var names []string
+	if abs, err := filepath.Abs(sourcePath); err == nil {
+		if info, err := os.Stat(abs); err == nil && !info.IsDir() {
+			abs = filepath.Dir(abs)
+		}
+		names = append(names, filepath.Base(abs))
+	}
+	return append(names, g.deployment.Name, container)
+}
+
+// parseImageUser parses the numeric uid and optional gid of an image user,
+// user names can not be resolved outside of the image
+func parseImageUser(user string) (uid, gid string, err error) {
+	if user == "" || user == "root" {
+		return "", "", nil
+	}
+	uid, gid, _ = strings.Cut(user, ":")
+	for _, id := range []string{uid, gid} {
+		if id == "" {
+			continue
+		}
+		if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+			return "", "", fmt.Errorf("image user %q is not numeric", user)
+		}
+	}
+	return uid, gid, nil
+}
+
+// originalRunSpec resolves the run spec of the container from the deployment stored before patching
+func (g Grapple) originalRunSpec(ctx context.Context, container string) (*runSpec, error) {
+	d, err := g.kubeCmd.GetDeploymentFromConfigMap(ctx, g.DeploymentConfigMapName(),
+		defaultConfigMapDeploymentKey)
+	if err != nil {
+		return nil, err
+	}
+	c, err := g.kubeCmd.GetContainerFromDeployment(container, d)
+	if err != nil {
+		return nil, err
+	}
+	ic, err := g.dockerCmd.GetImageConfig(ctx, c.Image)
+	if err != nil {
+		g.l.WithError(err).Warnf("couldnt inspect image %v, using the container spec only", c.Image)
+	}
+	rs := newRunSpec(c, ic, g.specEnvKeys(ctx, c))
+	return &rs, nil
+}
+
+// specEnvKeys lists the env names set through env and envFrom of the container
+func (g Grapple) specEnvKeys(ctx context.Context, c *core.Container) []string {
+	var keys []string
+	for _, env := range c.Env {
+		keys = append(keys, env.Name)
+	}
+	for _, source := range c.EnvFrom {
+		kind, name := "configmap", ""
+		switch {
+		case source.ConfigMapRef != nil:
+			name = source.ConfigMapRef.Name
+		case source.SecretRef != nil:
+			kind, name = "secret", source.SecretRef.Name
+		default:
+			continue
+		}
+		sourceKeys, err := g.kubeCmd.GetDataKeys(ctx, kind, name)
+		if err != nil {
+			g.l.WithError(err).Warnf("couldnt list keys of %v %q", kind, name)
+			continue
+		}
+		for _, key := range sourceKeys {
+			keys = append(keys, source.Prefix+key)
+		}
+	}
+	return keys
+}
+
+// imageUser returns the uid and gid the original image ran as, so the patched container keeps them,
+// nothing is returned if the security context of the deployment already sets the user
+func (g Grapple) imageUser(ctx context.Context, container, image string) (uid, gid string) {
+	c, err := g.kubeCmd.GetContainerFromDeployment(container, &g.deployment)
+	if err != nil {
+		g.l.WithError(err).Warn("couldnt get container for image user")
+		return "", ""
+	}
+	if sc := g.deployment.Spec.Template.Spec.SecurityContext; sc != nil && sc.RunAsUser != nil {
+		return "", ""
+	}
+	if c.SecurityContext != nil && c.SecurityContext.RunAsUser != nil {
+		return "", ""
+	}
+	ic, err := g.dockerCmd.GetImageConfig(ctx, image)
+	if err != nil {
+		g.l.WithError(err).Warnf("couldnt inspect image %v for its user", image)
+		return "", ""
+	}
+	uid, gid, err = parseImageUser(ic.User)
+	if err != nil {
+		g.l.WithError(err).Warn("the debug session will run as the user of the patch image")
+		return "", ""
+	}
+	return uid, gid
+}
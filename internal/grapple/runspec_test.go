@@ -0,0 +1,105 @@
+package grapple
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/foomo/gograpple/internal/exec"
+	core "k8s.io/api/core/v1"
+)
+
+func Test_newRunSpec(t *testing.T) {
+	image := &exec.ImageConfig{
+		Entrypoint: []string{"/app/server"},
+		Cmd:        []string{"--port", "80"},
+		WorkingDir: "/app",
+		Env:        []string{"PATH=/usr/bin", "LOG_LEVEL=info"},
+	}
+	tests := []struct {
+		name      string
+		container *core.Container
+		image     *exec.ImageConfig
+		specKeys  []string
+		want      runSpec
+	}{
+		{"image defaults", &core.Container{}, image, nil, runSpec{
+			Command:    []string{"/app/server", "--port", "80"},
+			WorkingDir: "/app",
+			Env:        []string{"PATH=/usr/bin", "LOG_LEVEL=info"},
+		}},
+		{"args replace image cmd", &core.Container{Args: []string{"--port", "8080"}}, image, nil, runSpec{
+			Command:    []string{"/app/server", "--port", "8080"},
+			WorkingDir: "/app",
+			Env:        []string{"PATH=/usr/bin", "LOG_LEVEL=info"},
+		}},
+		{"command ignores image cmd", &core.Container{Command: []string{"/bin/server"}, WorkingDir: "/srv"}, image, []string{"LOG_LEVEL"}, runSpec{
+			Command:    []string{"/bin/server"},
+			WorkingDir: "/srv",
+			Env:        []string{"PATH=/usr/bin"},
+		}},
+		{"no image config", &core.Container{Command: []string{"/bin/server"}, Args: []string{"-v"}}, nil, nil, runSpec{
+			Command: []string{"/bin/server", "-v"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newRunSpec(tt.container, tt.image, tt.specKeys); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newRunSpec() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_runSpec_split(t *testing.T) {
+	tests := []struct {
+		name        string
+		command     []string
+		names       []string
+		wantWrapper []string
+		wantArgs    []string
+		wantOK      bool
+	}{
+		{"binary", []string{"/app/server", "--port", "80"}, []string{"server"}, []string{}, []string{"--port", "80"}, true},
+		{"wrapper", []string{"/entrypoint.sh", "/app", "serve"}, []string{"search", "app"}, []string{"/entrypoint.sh"}, []string{"serve"}, true},
+		{"names in order", []string{"/usr/bin/tini", "--", "/bin/search", "search"}, []string{"search"}, []string{"/usr/bin/tini", "--"}, []string{"search"}, true},
+		{"path", []string{"/entrypoint.sh", "./bin/app"}, []string{"", "/srv/bin/app"}, []string{"/entrypoint.sh"}, []string{}, true},
+		{"no match", []string{"/entrypoint.sh", "/app", "serve"}, []string{"search"}, nil, []string{"/app", "serve"}, false},
+		{"no match keeps args", []string{"/usr/local/bin/server", "--port", "80"}, []string{"cmd", "api", "app"}, nil, []string{"--port", "80"}, false},
+		{"empty", nil, []string{"search"}, nil, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrapper, args, ok := runSpec{Command: tt.command}.split(tt.names...)
+			if !reflect.DeepEqual(wrapper, tt.wantWrapper) || !reflect.DeepEqual(args, tt.wantArgs) || ok != tt.wantOK {
+				t.Errorf("split() = %q, %q, %v, want %q, %q, %v", wrapper, args, ok, tt.wantWrapper, tt.wantArgs, tt.wantOK)
+			}
+		})
+	}
+}
+
+func Test_parseImageUser(t *testing.T) {
+	tests := []struct {
+		user    string
+		wantUID string
+		wantGID string
+		wantErr bool
+	}{
+		{"", "", "", false},
+		{"root", "", "", false},
+		{"1000", "1000", "", false},
+		{"1000:2000", "1000", "2000", false},
+		{"app", "", "", true},
+		{"1000:app", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.user, func(t *testing.T) {
+			uid, gid, err := parseImageUser(tt.user)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseImageUser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if uid != tt.wantUID || gid != tt.wantGID {
+				t.Errorf("parseImageUser() = %v, %v, want %v, %v", uid, gid, tt.wantUID, tt.wantGID)
+			}
+		})
+	}
+}
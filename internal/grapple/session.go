@@ -0,0 +1,233 @@
+package grapple
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const sessionAnnotation = "gograpple/session"
+
+// Session is the state of a debug session, it is persisted locally and on the pod so a session can be resumed
+type Session struct {
+	Cluster    string    `json:"cluster"`
+	Namespace  string    `json:"namespace"`
+	Deployment string    `json:"deployment"`
+	Container  string    `json:"container"`
+	Pod        string    `json:"pod"`
+	Host       string    `json:"host"`
+	LocalPort  int       `json:"local_port"`
+	PodPort    int       `json:"pod_port"`
+	Protocol   string    `json:"protocol"`
+	BinHash    string    `json:"bin_hash"`
+	BuildHash  string    `json:"build_hash"`
+	PatchImage string    `json:"patch_image"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+var sessionFileReplacer = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// SessionFile returns the local state file of the session of a deployment
+func SessionFile(cluster, namespace, deployment string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	name := sessionFileReplacer.ReplaceAllString(fmt.Sprintf("%v_%v_%v", cluster, namespace, deployment), "-")
+	return filepath.Join(dir, "gograpple", "sessions", name+".json"), nil
+}
+
+// LoadSession reads the local state of the session of a deployment
+func LoadSession(cluster, namespace, deployment string) (*Session, error) {
+	file, err := SessionFile(cluster, namespace, deployment)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("couldnt read session state %q: %w", file, err)
+	}
+	return &s, nil
+}
+
+func (s Session) save() error {
+	file, err := SessionFile(s.Cluster, s.Namespace, s.Deployment)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0600)
+}
+
+func removeSession(cluster, namespace, deployment string) error {
+	file, err := SessionFile(cluster, namespace, deployment)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Cluster sets the cluster the session state is saved for
+func (g *Grapple) Cluster(name string) {
+	g.cluster = name
+}
+
+// Resume reuses the pod, ports and binary of s, the binary is only copied again when it changed
+func (g *Grapple) Resume(s *Session) {
+	g.resumed = s
+}
+
+// FindSession returns the state of the session on the patched deployment, from the local state
+// or the annotation of the most recent pod if the local state is missing
+func (g Grapple) FindSession(ctx context.Context) (*Session, error) {
+	if !g.isPatched() {
+		return nil, fmt.Errorf("deployment %v is not patched, there is no session to resume", g.deployment.Name)
+	}
+	if s, err := LoadSession(g.cluster, g.deployment.Namespace, g.deployment.Name); err == nil {
+		return s, g.checkSession(ctx, s)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	pod, err := g.kubeCmd.GetMostRecentRunningPodBySelectors(ctx, g.deployment.Spec.Selector.MatchLabels)
+	if err != nil {
+		return nil, err
+	}
+	value, err := g.kubeCmd.GetPodAnnotation(ctx, pod, sessionAnnotation)
+	if err != nil {
+		return nil, err
+	}
+	if value == "" {
+		return nil, fmt.Errorf("no session found on pod %v", pod)
+	}
+	var s Session
+	if err := json.Unmarshal([]byte(value), &s); err != nil {
+		return nil, fmt.Errorf("couldnt read session annotation of pod %v: %w", pod, err)
+	}
+	return &s, g.checkSession(ctx, &s)
+}
+
+// checkSession makes sure the deployment still runs the patch image of the session
+func (g Grapple) checkSession(ctx context.Context, s *Session) error {
+	image, err := g.patchImage(ctx, s.Container)
+	if err != nil {
+		return err
+	}
+	if image != s.PatchImage {
+		return fmt.Errorf("deployment %v runs %v instead of the patch image %v of the session, patch it again",
+			g.deployment.Name, image, s.PatchImage)
+	}
+	return nil
+}
+
+// patchImage reads the image of container from the cluster, the cached deployment predates the patch
+func (g Grapple) patchImage(ctx context.Context, container string) (string, error) {
+	d, err := g.kubeCmd.GetDeployment(ctx, g.deployment.Name)
+	if err != nil {
+		return "", err
+	}
+	return g.kubeCmd.GetImage(ctx, *d, container)
+}
+
+// saveSession persists s locally and as annotation on its pod
+func (g Grapple) saveSession(ctx context.Context, s Session) error {
+	s.UpdatedAt = time.Now()
+	if err := s.save(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	_, err = g.kubeCmd.AnnotatePod(s.Pod, sessionAnnotation, string(data)).Quiet().Run(ctx)
+	return err
+}
+
+// unchangedBuild tells whether the resumed session built from the same sources and settings
+func (g Grapple) unchangedBuild(buildHash string) bool {
+	return g.resumed != nil && g.resumed.BuildHash != "" && g.resumed.BuildHash == buildHash
+}
+
+// unchangedBin tells whether the pod still runs the binary of the resumed session
+func (g Grapple) unchangedBin(ctx context.Context, pod, container, hash string) bool {
+	if g.resumed == nil || g.resumed.Pod != pod || g.resumed.BinHash != hash {
+		return false
+	}
+	_, err := g.kubeCmd.ExecPod(pod, container, []string{"test", "-x", g.binDestination()}).Quiet().Run(ctx)
+	return err == nil
+}
+
+func fileHash(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// sourcesHash hashes the build settings and the go files of the module in goModPath, directories ignored
+// by the go tool are skipped, sources outside of the module like replaced modules are not covered
+func sourcesHash(goModPath string, settings ...string) (string, error) {
+	h := sha256.New()
+	for _, setting := range settings {
+		fmt.Fprintf(h, "%v\x00", setting)
+	}
+	err := filepath.WalkDir(goModPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if p != goModPath && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !(strings.HasSuffix(name, ".go") || name == "go.mod" || name == "go.sum") {
+			return nil
+		}
+		rel, err := filepath.Rel(goModPath, p)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%v\x00", filepath.ToSlash(rel))
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(h, f)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
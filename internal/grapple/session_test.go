@@ -0,0 +1,82 @@
+package grapple
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestSession_save(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("cache dir is set by XDG_CACHE_HOME on linux only")
+	}
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	s := Session{Cluster: "arn:aws:eks:eu-central-1:123:cluster/dev", Namespace: "dev", Deployment: "app", Pod: "app-123", LocalPort: 40000, PodPort: 2346, BinHash: "abc"}
+	if err := s.save(); err != nil {
+		t.Fatal(err)
+	}
+	file, err := SessionFile(s.Cluster, s.Namespace, s.Deployment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "gograpple", "sessions", "arn-aws-eks-eu-central-1-123-cluster-dev_dev_app.json"); file != want {
+		t.Errorf("SessionFile() = %v, want %v", file, want)
+	}
+	got, err := LoadSession(s.Cluster, s.Namespace, s.Deployment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Pod != s.Pod || got.LocalPort != s.LocalPort || got.PodPort != s.PodPort || got.BinHash != s.BinHash {
+		t.Errorf("LoadSession() = %+v, want %+v", got, s)
+	}
+	if err := removeSession(s.Cluster, s.Namespace, s.Deployment); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSession(s.Cluster, s.Namespace, s.Deployment); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadSession() after remove error = %v, want not exist", err)
+	}
+	if err := removeSession(s.Cluster, s.Namespace, s.Deployment); err != nil {
+		t.Errorf("removeSession() of a missing session error = %v", err)
+	}
+}
+
+func Test_sourcesHash(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hash := func(settings ...string) string {
+		t.Helper()
+		h, err := sourcesHash(dir, settings...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	write("go.mod", "module example.com/app\n")
+	write("main.go", "package main\n")
+	base := hash("linux/amd64")
+	write("README.md", "docs")
+	write(".git/HEAD", "ref")
+	write("testdata/fixture.go", "package fixture\n")
+	if got := hash("linux/amd64"); got != base {
+		t.Errorf("sourcesHash() changed by files the build ignores")
+	}
+	if got := hash("linux/arm64"); got == base {
+		t.Errorf("sourcesHash() unchanged for other settings")
+	}
+	write("internal/lib.go", "package internal\n")
+	if got := hash("linux/amd64"); got == base {
+		t.Errorf("sourcesHash() unchanged for a new go file")
+	}
+}
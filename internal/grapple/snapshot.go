@@ -0,0 +1,196 @@
+package grapple
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/foomo/gograpple/internal/delve"
+	"github.com/foomo/gograpple/internal/kubectl"
+	"github.com/foomo/gograpple/internal/lifecycle"
+	"github.com/go-delve/delve/service/api"
+	"github.com/pkg/errors"
+)
+
+const (
+	snapshotGoroutinesFile = "goroutines.txt"
+	snapshotCoreFile       = "core"
+	snapshotPodDir         = "/tmp/gograpple-snapshot"
+	snapshotStackDepth     = 32
+)
+
+type frameCount struct {
+	Function string
+	Count    int
+}
+
+// Snapshot attaches dlv to the running bin, dumps all goroutine stacks and optionally a core file
+// into outDir and detaches again, leaving the process running
+func (g Grapple) Snapshot(namespace, container, bin, arch, host string, port int, outDir string, core bool) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pod, err := kubectl.GetMostRecentRunningPodBySelectors(namespace, g.deployment.Spec.Selector.MatchLabels)
+	if err != nil {
+		return err
+	}
+	dlvDest, err := ensureDelve(namespace, pod, container, arch)
+	if err != nil {
+		return err
+	}
+	pids, err := kubectl.GetPIDsOf(namespace, pod, container, bin)
+	if err != nil {
+		return err
+	}
+	if len(pids) != 1 {
+		return fmt.Errorf("found none or more than one process named %q", bin)
+	}
+	port, podPort, err := g.allocatePorts(host, port)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+
+	dslog := g.componentLog("server")
+	dslog.Infof("attaching delve to process %v", pids[0])
+	go func() {
+		if _, err := g.kubeCmd.ExecPod(pod, container, attachCmd(dlvDest, pids[0], host, podPort, false)).Quiet().Run(ctx); err != nil && ctx.Err() == nil {
+			dslog.WithError(err).Warn("delve server stopped")
+		}
+	}()
+	dclog := g.componentLog("client")
+	g.portForwardDelve(dclog, ctx, pod, host, port, podPort, delve.ProtocolRPC)
+	var dc *delve.KubeDelveClient
+	if err := tryCallWithContext(ctx, 10, time.Second, func(i int) error {
+		dclog.Infof("connecting to %v:%v (%d/%d)", host, port, i, 10)
+		dc, err = delve.NewKubeDelveClient(ctx, host, port)
+		return err
+	}); err != nil {
+		return err
+	}
+	// detach leaves the process running and stops the delve server, also when the session is shut down by a signal
+	var once sync.Once
+	var detachErr error
+	detach := func() error {
+		once.Do(func() {
+			detachErr = dc.Detach(false)
+		})
+		return detachErr
+	}
+	defer lifecycle.Register("detach delve from process", 30*time.Second, func(ctx context.Context) error {
+		return detach()
+	})()
+	defer func() {
+		if err := detach(); err != nil {
+			dclog.WithError(err).Warn("couldnt detach from process")
+		}
+	}()
+	if _, err := dc.Halt(); err != nil {
+		return err
+	}
+
+	grs, _, err := dc.ListGoroutines(0, 0)
+	if err != nil {
+		return err
+	}
+	goroutinesPath := filepath.Join(outDir, snapshotGoroutinesFile)
+	f, err := os.Create(goroutinesPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	for _, gr := range grs {
+		frames, err := dc.Stacktrace(gr.ID, snapshotStackDepth, 0, nil)
+		if err != nil {
+			return err
+		}
+		writeGoroutineStack(f, gr, frames)
+	}
+	g.l.Infof("wrote %d goroutine stacks to %v", len(grs), goroutinesPath)
+
+	if core {
+		if err := g.snapshotCore(namespace, pod, container, dc, outDir); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("%8v  %v\n", "count", "top user frame")
+	for _, fc := range groupByUserFrame(grs) {
+		fmt.Printf("%8d  %v\n", fc.Count, fc.Function)
+	}
+	return nil
+}
+
+func (g Grapple) snapshotCore(namespace, pod, container string, dc *delve.KubeDelveClient, outDir string) error {
+	dest := path.Join(snapshotPodDir, snapshotCoreFile)
+	if out, err := kubectl.ExecPod(namespace, pod, container, []string{"mkdir", "-p", snapshotPodDir}).String(); err != nil {
+		return errors.WithMessage(err, out)
+	}
+	defer func() {
+		_, _ = kubectl.ExecPod(namespace, pod, container, []string{"rm", "-rf", snapshotPodDir}).String()
+	}()
+	g.l.Infof("dumping core to %v", dest)
+	state, err := dc.CoreDumpStart(dest)
+	if err != nil {
+		return err
+	}
+	for state.Dumping {
+		g.l.Infof("dumping core: threads %d/%d, memory %d/%d", state.ThreadsDone, state.ThreadsTotal, state.MemDone, state.MemTotal)
+		state = dc.CoreDumpWait(1000)
+	}
+	if state.Err != "" {
+		return fmt.Errorf("core dump failed: %v", state.Err)
+	}
+	corePath := filepath.Join(outDir, snapshotCoreFile)
+	g.l.Infof("copying core to %v", corePath)
+	return kubectl.CopyFromPod(namespace, pod, container, dest, corePath)
+}
+
+func writeGoroutineStack(w io.Writer, gr *api.Goroutine, frames []api.Stackframe) {
+	fmt.Fprintf(w, "goroutine %d", gr.ID)
+	if gr.ThreadID != 0 {
+		fmt.Fprintf(w, " [thread %d]", gr.ThreadID)
+	}
+	fmt.Fprintln(w, ":")
+	for _, frame := range frames {
+		name := "?"
+		if frame.Function != nil {
+			name = frame.Function.Name()
+		}
+		fmt.Fprintf(w, "%v()\n\t%v:%d\n", name, frame.File, frame.Line)
+	}
+	fmt.Fprintln(w)
+}
+
+// groupByUserFrame counts goroutines by the function of their topmost frame in user code,
+// goroutines without user frames are counted by their current location
+func groupByUserFrame(grs []*api.Goroutine) []frameCount {
+	counts := map[string]int{}
+	for _, gr := range grs {
+		name := "?"
+		if gr.UserCurrentLoc.Function != nil {
+			name = gr.UserCurrentLoc.Function.Name()
+		} else if gr.CurrentLoc.Function != nil {
+			name = gr.CurrentLoc.Function.Name()
+		}
+		counts[name]++
+	}
+	var fcs []frameCount
+	for name, count := range counts {
+		fcs = append(fcs, frameCount{name, count})
+	}
+	sort.Slice(fcs, func(i, j int) bool {
+		if fcs[i].Count == fcs[j].Count {
+			return fcs[i].Function < fcs[j].Function
+		}
+		return fcs[i].Count > fcs[j].Count
+	})
+	return fcs
+}
@@ -0,0 +1,33 @@
+package grapple
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-delve/delve/service/api"
+)
+
+func Test_groupByUserFrame(t *testing.T) {
+	gr := func(current, user string) *api.Goroutine {
+		g := &api.Goroutine{}
+		if current != "" {
+			g.CurrentLoc.Function = &api.Function{Name_: current}
+		}
+		if user != "" {
+			g.UserCurrentLoc.Function = &api.Function{Name_: user}
+		}
+		return g
+	}
+	grs := []*api.Goroutine{
+		gr("runtime.gopark", "main.worker"),
+		gr("main.main", "main.main"),
+		gr("runtime.selectgo", "main.worker"),
+		gr("", ""),
+		gr("runtime.gopark", "net/http.(*conn).serve"),
+		gr("runtime.gopark", ""),
+	}
+	want := []frameCount{{"main.worker", 2}, {"?", 1}, {"main.main", 1}, {"net/http.(*conn).serve", 1}, {"runtime.gopark", 1}}
+	if got := groupByUserFrame(grs); !reflect.DeepEqual(got, want) {
+		t.Errorf("groupByUserFrame() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,70 @@
+package grapple
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// substitutePath maps a local source directory (From) to the directory
+// the sources were located in when the binary was compiled (To),
+// the rules are applied by the debug client, a headless dlv server ignores them
+type substitutePath struct {
+	From string `json:"from" yaml:"from"`
+	To   string `json:"to" yaml:"to"`
+}
+
+// newSubstitutePaths returns the rules needed to map the local module root
+// onto the module root recorded in the debug info of the built binary
+func newSubstitutePaths(localRoot, buildRoot string) []substitutePath {
+	localRoot = trimTrailingSlash(filepath.ToSlash(localRoot))
+	buildRoot = trimTrailingSlash(buildRoot)
+	if buildRoot == "" || localRoot == buildRoot {
+		return nil
+	}
+	return []substitutePath{{From: localRoot, To: buildRoot}}
+}
+
+// debugBuildSubstitutePaths returns the rules for the debug build of the module in goModPath,
+// a -trimpath build records the module path instead of the local module root
+func debugBuildSubstitutePaths(goModPath string, trimPath bool) ([]substitutePath, error) {
+	if !trimPath {
+		return nil, nil
+	}
+	buildRoot, err := modulePath(goModPath)
+	if err != nil {
+		return nil, err
+	}
+	return newSubstitutePaths(goModPath, buildRoot), nil
+}
+
+func trimTrailingSlash(p string) string {
+	if len(p) > 1 {
+		return strings.TrimSuffix(p, "/")
+	}
+	return p
+}
+
+// modulePath reads the module path from the go.mod in goModDir,
+// which is the path recorded for module sources when building with -trimpath
+func modulePath(goModDir string) (string, error) {
+	f, err := os.Open(filepath.Join(goModDir, "go.mod"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// module example.com/app // comment
+		line := strings.TrimSpace(strings.SplitN(scanner.Text(), "//", 2)[0])
+		if fields := strings.Fields(line); len(fields) == 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], "`\""), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no module directive found in %q", goModDir)
+}
@@ -0,0 +1,87 @@
+package grapple
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_newSubstitutePaths(t *testing.T) {
+	type args struct {
+		localRoot string
+		buildRoot string
+	}
+	tests := []struct {
+		name string
+		args args
+		want []substitutePath
+	}{
+		{"same", args{"/home/dev/app", "/home/dev/app"}, nil},
+		{"empty", args{"/home/dev/app", ""}, nil},
+		{"container", args{"/home/dev/app/", "/"}, []substitutePath{{"/home/dev/app", "/"}}},
+		{"trimpath", args{"/home/dev/app", "github.com/foomo/app"}, []substitutePath{{"/home/dev/app", "github.com/foomo/app"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newSubstitutePaths(tt.args.localRoot, tt.args.buildRoot); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("newSubstitutePaths() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_modulePath(t *testing.T) {
+	tests := []struct {
+		name    string
+		goMod   string
+		want    string
+		wantErr bool
+	}{
+		{"module", "module github.com/foomo/app\n\ngo 1.18\n", "github.com/foomo/app", false},
+		{"quoted with comment", "// app\nmodule \"github.com/foomo/app\" // deprecated\n", "github.com/foomo/app", false},
+		{"prefix", "modulefoo bar\nmodule github.com/foomo/app\n", "github.com/foomo/app", false},
+		{"missing", "go 1.18\n", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte(tt.goMod), 0600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := modulePath(dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("modulePath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("modulePath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_debugBuildSubstitutePaths(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module github.com/foomo/app\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		trimPath bool
+		want     []substitutePath
+	}{
+		{"local root", false, nil},
+		{"trimpath", true, []substitutePath{{filepath.ToSlash(dir), "github.com/foomo/app"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := debugBuildSubstitutePaths(dir, tt.trimPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("debugBuildSubstitutePaths() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package grapple
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const syncInterval = time.Second
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// mountSync tracks the files of a local mount dir to find what changed since the last sync
+type mountSync struct {
+	mount Mount
+	files map[string]fileState
+}
+
+func newMountSync(m Mount) *mountSync {
+	return &mountSync{mount: m, files: map[string]fileState{}}
+}
+
+// changes lists the files changed or removed since the last call, relative to the host path
+func (ms *mountSync) changes() (changed, removed []string, err error) {
+	files := map[string]fileState{}
+	err = filepath.WalkDir(ms.mount.HostPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(ms.mount.HostPath, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		files[rel] = fileState{info.ModTime(), info.Size()}
+		if prev, ok := ms.files[rel]; !ok || prev != files[rel] {
+			changed = append(changed, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	for rel := range ms.files {
+		if _, ok := files[rel]; !ok {
+			removed = append(removed, rel)
+		}
+	}
+	sort.Strings(removed)
+	ms.files = files
+	return changed, removed, nil
+}
+
+// reset forgets the synced files, so the next sync copies everything
+func (ms *mountSync) reset() {
+	ms.files = map[string]fileState{}
+}
+
+// tarFiles writes the files relative to root into a tar stream
+func tarFiles(root string, files []string) (*bytes.Buffer, error) {
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	for _, rel := range files {
+		p := filepath.Join(root, filepath.FromSlash(rel))
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return nil, err
+		}
+		hdr.Name = rel
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, err
+		}
+		_, err = io.Copy(tw, f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf, tw.Close()
+}
+
+// SyncMounts copies the local mount dirs into the emptyDir volumes of the patched pod
+// and keeps them in sync until ctx is done
+func (g Grapple) SyncMounts(ctx context.Context, container string, mounts []Mount) {
+	l := g.componentLog("sync")
+	syncs := make([]*mountSync, len(mounts))
+	for i, m := range mounts {
+		syncs[i] = newMountSync(m)
+	}
+	pod := ""
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+	for {
+		if pod == "" {
+			var err error
+			if pod, err = g.waitForMounts(ctx, container, mounts); err != nil {
+				if ctx.Err() == nil {
+					l.WithError(err).Error("couldnt find patched pod for syncing mounts")
+				}
+				return
+			}
+			for _, ms := range syncs {
+				ms.reset()
+			}
+		}
+		for _, ms := range syncs {
+			if err := g.syncMount(ctx, l, pod, container, ms); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				// the pod might have been replaced, emptyDir volumes start empty again
+				l.WithError(err).Warnf("couldnt sync %v, resolving pod again", ms.mount.HostPath)
+				pod = ""
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (g Grapple) syncMount(ctx context.Context, l *logrus.Entry, pod, container string, ms *mountSync) error {
+	changed, removed, err := ms.changes()
+	if err != nil {
+		return err
+	}
+	if len(changed) > 0 {
+		buf, err := tarFiles(ms.mount.HostPath, changed)
+		if err != nil {
+			return err
+		}
+		cmd := []string{"tar", "-xf", "-", "-C", ms.mount.MountPath}
+		if out, err := g.kubeCmd.ExecPodInput(pod, container, cmd, buf).Quiet().Run(ctx); err != nil {
+			ms.reset()
+			return errors.WithMessage(err, out)
+		}
+		l.Infof("synced %d files from %v to %v", len(changed), ms.mount.HostPath, ms.mount.MountPath)
+	}
+	if len(removed) > 0 {
+		cmd := []string{"rm", "-f", "--"}
+		for _, rel := range removed {
+			cmd = append(cmd, path.Join(ms.mount.MountPath, rel))
+		}
+		if out, err := g.kubeCmd.ExecPod(pod, container, cmd).Quiet().Run(ctx); err != nil {
+			return errors.WithMessage(err, out)
+		}
+		l.Infof("removed %d files from %v", len(removed), ms.mount.MountPath)
+	}
+	return nil
+}
+
+// waitForMounts waits for a running pod that has all mount paths
+func (g Grapple) waitForMounts(ctx context.Context, container string, mounts []Mount) (string, error) {
+	var pod string
+	err := tryCallWithContext(ctx, 60, time.Second, func(i int) error {
+		var err error
+		pod, err = g.kubeCmd.GetMostRecentRunningPodBySelectors(ctx, g.deployment.Spec.Selector.MatchLabels)
+		if err != nil {
+			return err
+		}
+		for _, m := range mounts {
+			cmd := []string{"test", "-d", m.MountPath}
+			if _, err := g.kubeCmd.ExecPod(pod, container, cmd).Quiet().Run(ctx); err != nil {
+				return errors.WithMessagef(err, "mount path %v not ready", m.MountPath)
+			}
+		}
+		return nil
+	})
+	return pod, err
+}
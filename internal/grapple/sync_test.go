@@ -0,0 +1,73 @@
+package grapple
+
+import (
+	"archive/tar"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_mountSync_changes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string, modTime time.Time) {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	write("index.html", "<html>", start)
+	write("static/app.css", "body{}", start)
+
+	ms := newMountSync(Mount{HostPath: dir, MountPath: "/www"})
+	steps := []struct {
+		name        string
+		change      func()
+		wantChanged []string
+		wantRemoved []string
+	}{
+		{"initial sync", func() {}, []string{"index.html", "static/app.css"}, nil},
+		{"unchanged", func() {}, nil, nil},
+		{"modified", func() { write("static/app.css", "body{margin:0}", start.Add(time.Minute)) }, []string{"static/app.css"}, nil},
+		{"removed", func() { os.Remove(filepath.Join(dir, "index.html")) }, nil, []string{"index.html"}},
+	}
+	for _, step := range steps {
+		step.change()
+		changed, removed, err := ms.changes()
+		if err != nil {
+			t.Fatalf("%v: changes() error = %v", step.name, err)
+		}
+		if !reflect.DeepEqual(changed, step.wantChanged) || !reflect.DeepEqual(removed, step.wantRemoved) {
+			t.Errorf("%v: changes() = %v, %v, want %v, %v", step.name, changed, removed, step.wantChanged, step.wantRemoved)
+		}
+	}
+}
+
+func Test_tarFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "conf"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "conf", "app.yaml"), []byte("a: 1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := tarFiles(dir, []string{"conf/app.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdr, err := tar.NewReader(buf).Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hdr.Name != "conf/app.yaml" || hdr.Size != 4 {
+		t.Errorf("tarFiles() header = %v (%d bytes), want conf/app.yaml (4 bytes)", hdr.Name, hdr.Size)
+	}
+}
@@ -0,0 +1,36 @@
+package grapple
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/foomo/gograpple/internal/delve"
+	"github.com/sirupsen/logrus"
+)
+
+// Connect opens an interactive terminal debugger on an already forwarded delve server,
+// local locations are mapped onto the paths of the debug build of sourcePath
+func Connect(l *logrus.Entry, sourcePath, host string, port int, trimPath bool) error {
+	goModPath, err := findGoProjectRoot(sourcePath)
+	if err != nil {
+		return fmt.Errorf("couldnt find go.mod path for source %q", sourcePath)
+	}
+	substitutePaths, err := debugBuildSubstitutePaths(goModPath, trimPath)
+	if err != nil {
+		return err
+	}
+	l.Infof("connecting to delve server on %v:%v", host, port)
+	dc, err := delve.NewKubeDelveClient(context.Background(), host, port)
+	if err != nil {
+		return err
+	}
+	defer dc.Close()
+	if err := dc.ValidateState(); err != nil {
+		return err
+	}
+	t := delve.NewTerminal(dc, goModPath)
+	for _, sp := range substitutePaths {
+		t.SubstitutePath(sp.From, sp.To)
+	}
+	return t.Run()
+}
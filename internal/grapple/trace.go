@@ -0,0 +1,78 @@
+package grapple
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/foomo/gograpple/internal/delve"
+	"github.com/foomo/gograpple/internal/lifecycle"
+	"github.com/sirupsen/logrus"
+)
+
+// Trace sets tracepoints on an already forwarded delve server and streams their hits as json lines
+// until ctx is done, tracepoints are cleared and the client disconnects without stopping the program,
+// also when the session is shut down by a signal. Local locations are mapped onto the paths of the
+// debug build of sourcePath
+func Trace(ctx context.Context, l *logrus.Entry, sourcePath, host string, port int, trimPath bool, locations, exprs []string) error {
+	goModPath, err := findGoProjectRoot(sourcePath)
+	if err != nil {
+		return fmt.Errorf("couldnt find go.mod path for source %q", sourcePath)
+	}
+	substitutePaths, err := debugBuildSubstitutePaths(goModPath, trimPath)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	l.Infof("connecting to delve server on %v:%v", host, port)
+	dc, err := delve.NewKubeDelveClient(ctx, host, port)
+	if err != nil {
+		return err
+	}
+	defer dc.Close()
+	if err := dc.ValidateState(); err != nil {
+		return err
+	}
+	// breakpoints can only be set on a halted program
+	if _, err := dc.Halt(); err != nil {
+		return err
+	}
+	t := delve.NewTracer(dc, os.Stdout)
+	for _, sp := range substitutePaths {
+		t.SubstitutePath(sp.From, sp.To)
+	}
+	var once sync.Once
+	clearTracepoints := func() {
+		once.Do(func() {
+			l.Info("clearing tracepoints")
+			if err := t.Clear(); err != nil {
+				l.WithError(err).Warn("couldnt clear tracepoints")
+			}
+			if err := dc.Disconnect(true); err != nil {
+				l.WithError(err).Warn("couldnt disconnect from delve server")
+			}
+		})
+	}
+	streamed := make(chan struct{})
+	defer lifecycle.Register("clear tracepoints", 30*time.Second, func(stepCtx context.Context) error {
+		// ending the stream halts the program, so the tracepoints can be cleared
+		cancel()
+		select {
+		case <-streamed:
+		case <-stepCtx.Done():
+			return stepCtx.Err()
+		}
+		clearTracepoints()
+		return nil
+	})()
+	defer clearTracepoints()
+	defer close(streamed)
+	if err := t.SetTracepoints(locations, exprs); err != nil {
+		return err
+	}
+	l.Infof("tracing %v", locations)
+	return t.Stream(ctx)
+}
@@ -15,28 +15,55 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	IDENone   = "none"
+	IDEVSCode = "vscode"
+	IDEGoland = "goland"
+)
+
+func ValidateIDE(ide string) error {
+	switch ide {
+	case IDENone, IDEVSCode, IDEGoland:
+		return nil
+	}
+	return fmt.Errorf("invalid ide %q, expected one of %q, %q or %q", ide, IDENone, IDEVSCode, IDEGoland)
+}
+
+// launchConfigName is the stable name used for generated ide debug configurations
+func launchConfigName(deployment string) string {
+	return fmt.Sprintf("gograpple: %v", deployment)
+}
+
 type launchArgs struct {
-	Name       string `json:"name,omitempty"`
-	Request    string `json:"request,omitempty"`
-	Type       string `json:"type,omitempty"`
-	Mode       string `json:"mode,omitempty"`
-	RemotePath string `json:"remotePath,omitempty"`
-	Port       int    `json:"port,omitempty"`
-	Host       string `json:"host,omitempty"`
-	Trace      string `json:"trace,omitempty"`
-	LogOutput  string `json:"logOutput,omitempty"`
-	ShowLog    bool   `json:"showLog,omitempty"`
+	Name         string   `json:"name,omitempty"`
+	Request      string   `json:"request,omitempty"`
+	Type         string   `json:"type,omitempty"`
+	DebugAdapter string   `json:"debugAdapter,omitempty"`
+	Mode         string   `json:"mode,omitempty"`
+	Program      string   `json:"program,omitempty"`
+	Args         []string `json:"args,omitempty"`
+	Cwd          string   `json:"cwd,omitempty"`
+	ProcessID    int      `json:"processId,omitempty"`
+	RemotePath   string   `json:"remotePath,omitempty"`
+	Port         int      `json:"port,omitempty"`
+	Host         string   `json:"host,omitempty"`
+	Trace        string   `json:"trace,omitempty"`
+	LogOutput    string   `json:"logOutput,omitempty"`
+	ShowLog      bool     `json:"showLog,omitempty"`
+
+	SubstitutePath []substitutePath `json:"substitutePath,omitempty"`
 }
 
-func newLaunchArgs(host string, port int, workspaceFolder string) *launchArgs {
+func newLaunchArgs(name, host string, port int, substitutePaths []substitutePath) *launchArgs {
 	return &launchArgs{
-		Host:       host,
-		Name:       fmt.Sprintf("delve-%v", time.Now().Unix()),
-		Port:       port,
-		Request:    "attach",
-		Type:       "go",
-		Mode:       "remote",
-		RemotePath: workspaceFolder,
+		Host:    host,
+		Name:    name,
+		Port:    port,
+		Request: "attach",
+		Type:    "go",
+		Mode:    "remote",
+
+		SubstitutePath: substitutePaths,
 
 		// Trace:      "verbose",
 		// LogOutput: "rpc",
@@ -44,6 +71,39 @@ func newLaunchArgs(host string, port int, workspaceFolder string) *launchArgs {
 	}
 }
 
+// newDAPLaunchArgs connects to a dlv dap server, which will exec the program on launch
+func newDAPLaunchArgs(name, host string, port int, program string, args []string, substitutePaths []substitutePath) *launchArgs {
+	return &launchArgs{
+		Host:         host,
+		Name:         name,
+		Port:         port,
+		Request:      "launch",
+		Type:         "go",
+		DebugAdapter: "dlv-dap",
+		Mode:         "exec",
+		Program:      program,
+		Args:         args,
+
+		SubstitutePath: substitutePaths,
+	}
+}
+
+// newDAPAttachArgs connects to a dlv dap server, which will attach to the process on the pod
+func newDAPAttachArgs(name, host string, port, pid int, substitutePaths []substitutePath) *launchArgs {
+	return &launchArgs{
+		Host:         host,
+		Name:         name,
+		Port:         port,
+		Request:      "attach",
+		Type:         "go",
+		DebugAdapter: "dlv-dap",
+		Mode:         "local",
+		ProcessID:    pid,
+
+		SubstitutePath: substitutePaths,
+	}
+}
+
 func (la *launchArgs) toJson() (string, error) {
 	bytes, err := json.Marshal(la)
 	if err != nil {
@@ -52,20 +112,49 @@ func (la *launchArgs) toJson() (string, error) {
 	return string(bytes), nil
 }
 
-func launchVSCode(ctx context.Context, l *logrus.Entry, goModDir, host string, port, tries int) error {
-	openFile := goModDir
-	workspaceFolder := "${workspaceFolder}"
-	// is there a workspace in that dir
-	files, errReadDir := os.ReadDir(goModDir)
+// findWorkspaceFile returns the .code-workspace file in dir, if there is one
+func findWorkspaceFile(dir string) string {
+	files, errReadDir := os.ReadDir(dir)
 	if errReadDir == nil {
 		for _, file := range files {
 			if !file.IsDir() && strings.HasSuffix(file.Name(), ".code-workspace") {
-				openFile = filepath.Join(goModDir, file.Name())
-				workspaceFolder = goModDir
-				break
+				return filepath.Join(dir, file.Name())
 			}
 		}
 	}
+	return ""
+}
+
+// writeVSCodeLaunchConfig merges the launch args into the workspace file or .vscode/launch.json
+func writeVSCodeLaunchConfig(l *logrus.Entry, goModDir string, la *launchArgs) error {
+	workspaceFile := findWorkspaceFile(goModDir)
+	if la.Mode == "remote" {
+		la.RemotePath = "${workspaceFolder}"
+		if workspaceFile != "" {
+			la.RemotePath = goModDir
+		}
+	}
+	p, err := writeLaunchConfig(goModDir, workspaceFile, la)
+	if err != nil {
+		return err
+	}
+	l.Infof("wrote debug configuration %q to %v", la.Name, p)
+	return nil
+}
+
+func launchVSCode(ctx context.Context, l *logrus.Entry, goModDir string, la *launchArgs, tries int, launchJSON bool) error {
+	openFile := goModDir
+	workspaceFolder := "${workspaceFolder}"
+	// is there a workspace in that dir
+	if workspaceFile := findWorkspaceFile(goModDir); workspaceFile != "" {
+		openFile = workspaceFile
+		workspaceFolder = goModDir
+	}
+	if launchJSON {
+		if err := writeVSCodeLaunchConfig(l, goModDir, la); err != nil {
+			return err
+		}
+	}
 
 	exec.NewCommand("code").Logger(l).Args(openFile).PostEnd(func() error {
 		return tryCallWithContext(ctx, tries, 200*time.Millisecond, func(i int) error {
@@ -75,12 +164,19 @@ func launchVSCode(ctx context.Context, l *logrus.Entry, goModDir, host string, p
 		})
 	}).Run(ctx)
 
+	if launchJSON {
+		l.Infof("start %q from the run and debug view", la.Name)
+		return nil
+	}
 	l.Infof("opening debug configuration")
-	la, err := newLaunchArgs(host, port, workspaceFolder).toJson()
+	if la.Mode == "remote" {
+		la.RemotePath = workspaceFolder
+	}
+	laJson, err := la.toJson()
 	if err != nil {
 		return err
 	}
-	_, err = util.Open(l, ctx, `vscode://fabiospampinato.vscode-debug-launcher/launch?args=`+url.QueryEscape(la))
+	_, err = util.Open(l, ctx, `vscode://fabiospampinato.vscode-debug-launcher/launch?args=`+url.QueryEscape(laJson))
 	if err != nil {
 		return err
 	}
@@ -177,8 +177,8 @@ func TempSwitchContext(context string, cb func() error) error {
 	return cb()
 }
 
-func PortForwardPod(namespace, pod string, port int) error {
-	cmd := fmt.Sprintf("kubectl -n %v port-forward pods/%v %v:%v", namespace, pod, port, port)
+func PortForwardPod(namespace, pod string, localPort, podPort int) error {
+	cmd := fmt.Sprintf("kubectl -n %v port-forward pods/%v %v:%v", namespace, pod, localPort, podPort)
 	_, err := script.Exec(cmd).WithStdout(log.Writer("kubectl")).Stdout()
 	// if err != nil {
 	// 	return errors.WithMessage(err, out)
@@ -213,3 +213,11 @@ func CopyToPod(namespace, pod, container, source, destination string) error {
 	}
 	return nil
 }
+
+func CopyFromPod(namespace, pod, container, source, destination string) error {
+	out, err := script.Exec(fmt.Sprintf("kubectl -n %v cp %v:%v %v -c %v", namespace, pod, source, destination, container)).String()
+	if err != nil {
+		return errors.WithMessage(err, out)
+	}
+	return nil
+}
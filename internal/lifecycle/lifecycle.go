@@ -0,0 +1,217 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultTimeout bounds a cleanup step registered without a timeout
+const DefaultTimeout = 30 * time.Second
+
+// Signals shut down the session, unless an interrupt is claimed
+var Signals = []os.Signal{os.Interrupt, syscall.SIGTERM, syscall.SIGHUP}
+
+// Result reports the outcome of a cleanup step
+type Result struct {
+	Name     string
+	Err      error
+	Duration time.Duration
+}
+
+type step struct {
+	id      int
+	name    string
+	timeout time.Duration
+	fn      func(ctx context.Context) error
+}
+
+// Manager runs registered cleanup steps in reverse order once the session ends or is signaled
+type Manager struct {
+	mu       sync.Mutex
+	steps    []step
+	nextID   int
+	claims   []chan os.Signal
+	once     sync.Once
+	results  []Result
+	listened bool
+}
+
+func New() *Manager {
+	return &Manager{}
+}
+
+// Register adds a cleanup step, the returned func removes it again when its resource is gone
+func (m *Manager) Register(name string, timeout time.Duration, fn func(ctx context.Context) error) (deregister func()) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	id := m.nextID
+	m.steps = append(m.steps, step{id, name, timeout, fn})
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for i, s := range m.steps {
+			if s.id == id {
+				m.steps = append(m.steps[:i], m.steps[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Claim routes interrupts to the returned channel instead of shutting down, until release is called,
+// the most recent claim wins
+func (m *Manager) Claim() (interrupts <-chan os.Signal, release func()) {
+	c := make(chan os.Signal, 1)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.claims = append(m.claims, c)
+	return c, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for i, claim := range m.claims {
+			if claim == c {
+				m.claims = append(m.claims[:i], m.claims[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// NotifyContext returns a context canceled on the first claimed interrupt
+func (m *Manager) NotifyContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	interrupts, release := m.Claim()
+	go func() {
+		select {
+		case <-interrupts:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		release()
+		cancel()
+	}
+}
+
+// Listen handles the shutdown signals, unclaimed ones run the cleanup and exit with 128 + the signal number
+func (m *Manager) Listen(l *logrus.Entry) {
+	m.mu.Lock()
+	if m.listened {
+		m.mu.Unlock()
+		return
+	}
+	m.listened = true
+	m.mu.Unlock()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, Signals...)
+	go func() {
+		for sig := range signals {
+			if sig == os.Interrupt && m.forward(sig) {
+				continue
+			}
+			l.Infof("received %v, cleaning up", sig)
+			m.Cleanup(l)
+			code := 1
+			if s, ok := sig.(syscall.Signal); ok {
+				code = 128 + int(s)
+			}
+			os.Exit(code)
+		}
+	}()
+}
+
+func (m *Manager) forward(sig os.Signal) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.claims) == 0 {
+		return false
+	}
+	select {
+	case m.claims[len(m.claims)-1] <- sig:
+	default:
+		// the previous interrupt is still pending
+	}
+	return true
+}
+
+// Cleanup runs the registered steps once in reverse order, each bounded by its timeout,
+// concurrent calls wait for the first one and get the same results
+func (m *Manager) Cleanup(l *logrus.Entry) []Result {
+	m.once.Do(func() {
+		m.mu.Lock()
+		steps := append([]step{}, m.steps...)
+		m.mu.Unlock()
+		for i := len(steps) - 1; i >= 0; i-- {
+			r := runStep(steps[i])
+			entry := l.WithField("step", r.Name).WithField("duration_ms", r.Duration.Milliseconds())
+			if r.Err != nil {
+				entry.WithError(r.Err).Warnf("cleanup %v failed", r.Name)
+			} else {
+				entry.Debugf("cleanup %v done", r.Name)
+			}
+			m.results = append(m.results, r)
+		}
+	})
+	return m.results
+}
+
+func runStep(s step) Result {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	start := time.Now()
+	errs := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errs <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		errs <- s.fn(ctx)
+	}()
+	var err error
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+		err = fmt.Errorf("timed out after %v", s.timeout)
+	}
+	return Result{Name: s.name, Err: err, Duration: time.Since(start)}
+}
+
+var std = New()
+
+// Register adds a cleanup step to the session
+func Register(name string, timeout time.Duration, fn func(ctx context.Context) error) (deregister func()) {
+	return std.Register(name, timeout, fn)
+}
+
+// Claim routes interrupts of the session to the returned channel until release is called
+func Claim() (interrupts <-chan os.Signal, release func()) {
+	return std.Claim()
+}
+
+// NotifyContext returns a context canceled on the first interrupt of the session
+func NotifyContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return std.NotifyContext(parent)
+}
+
+// Listen handles the shutdown signals of the session
+func Listen(l *logrus.Entry) {
+	std.Listen(l)
+}
+
+// Cleanup runs the cleanup steps of the session
+func Cleanup(l *logrus.Entry) []Result {
+	return std.Cleanup(l)
+}
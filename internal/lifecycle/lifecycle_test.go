@@ -0,0 +1,84 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestManager_Cleanup(t *testing.T) {
+	m := New()
+	var order []string
+	add := func(name string, timeout time.Duration, err error) func() {
+		return m.Register(name, timeout, func(ctx context.Context) error {
+			order = append(order, name)
+			return err
+		})
+	}
+	add("remove workdir", 0, nil)
+	add("rollback", 0, errors.New("not patched"))
+	deregister := add("removed", 0, nil)
+	m.Register("slow", 10*time.Millisecond, func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	m.Register("panics", 0, func(ctx context.Context) error {
+		panic("boom")
+	})
+	add("stop port-forwards", 0, nil)
+	deregister()
+
+	results := m.Cleanup(logrus.NewEntry(logrus.New()))
+	if want := []string{"stop port-forwards", "rollback", "remove workdir"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("Cleanup() order = %v, want %v", order, want)
+	}
+	var names, failed []string
+	for _, r := range results {
+		names = append(names, r.Name)
+		if r.Err != nil {
+			failed = append(failed, r.Name)
+		}
+	}
+	if want := []string{"stop port-forwards", "panics", "slow", "rollback", "remove workdir"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Cleanup() results = %v, want %v", names, want)
+	}
+	if want := []string{"panics", "slow", "rollback"}; !reflect.DeepEqual(failed, want) {
+		t.Errorf("Cleanup() failed = %v, want %v", failed, want)
+	}
+	if again := m.Cleanup(logrus.NewEntry(logrus.New())); len(again) != len(results) || len(order) != 3 {
+		t.Errorf("Cleanup() ran twice")
+	}
+}
+
+func TestManager_Claim(t *testing.T) {
+	m := New()
+	if m.forward(os.Interrupt) {
+		t.Fatal("forward() without claim = true, want false")
+	}
+	first, releaseFirst := m.Claim()
+	second, releaseSecond := m.Claim()
+	if !m.forward(os.Interrupt) {
+		t.Fatal("forward() with claim = false, want true")
+	}
+	select {
+	case <-second:
+	default:
+		t.Error("most recent claim didnt receive the interrupt")
+	}
+	releaseSecond()
+	m.forward(os.Interrupt)
+	select {
+	case <-first:
+	default:
+		t.Error("previous claim didnt receive the interrupt after release")
+	}
+	releaseFirst()
+	if m.forward(os.Interrupt) {
+		t.Error("forward() after release = true, want false")
+	}
+}
@@ -3,25 +3,29 @@ package util
 import (
 	"context"
 	"fmt"
-	"os"
-	"os/signal"
 	"runtime"
 	"strings"
 	"time"
 
 	"github.com/foomo/gograpple/internal/exec"
+	"github.com/foomo/gograpple/internal/lifecycle"
 	"github.com/sirupsen/logrus"
 )
 
-func RunWithInterrupt(l *logrus.Entry, callback func(ctx context.Context)) {
-	signalChan := make(chan os.Signal, 1)
-	signal.Notify(signalChan, os.Interrupt)
+// RunWithInterrupt runs callback until interrupted, it is run again unless a second interrupt follows,
+// the loop ends when parent is done
+func RunWithInterrupt(parent context.Context, l *logrus.Entry, callback func(ctx context.Context)) {
+	signalChan, release := lifecycle.Claim()
+	defer release()
 	durReload := 3 * time.Second
 	for {
-		ctx, cancelCtx := context.WithCancel(context.Background())
+		ctx, cancelCtx := context.WithCancel(parent)
 		// do stuff
 		go callback(ctx)
 		select {
+		case <-parent.Done():
+			cancelCtx()
+			return
 		case <-signalChan: // first signal
 			l.Info("-")
 			l.Infof("interrupt received, trigger one more within %v to terminate", durReload)
@@ -33,7 +37,6 @@ func RunWithInterrupt(l *logrus.Entry, callback func(ctx context.Context)) {
 			case <-signalChan: // second signal, hard exit
 				l.Info("-")
 				l.Info("terminating")
-				signal.Stop(signalChan)
 				// exit loop
 				return
 			}